@@ -0,0 +1,38 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/krystofrezac/lifebuoy/internal/configuration"
+)
+
+func runConfig(args []string) error {
+	flags := flag.NewFlagSet("config", flag.ExitOnError)
+	environment := flags.String("environment", "", "Same as the server flag. Name of the environment whose overlay is applied")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: lifebuoy config [flags] <app name> [configuration root, default .]")
+		fmt.Fprintln(flags.Output(), "Prints the app configuration merged with the defaults, the profiles it extends and the environment overlay")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() < 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	appName := flags.Arg(0)
+	root := "."
+	if flags.NArg() > 1 {
+		root = flags.Arg(1)
+	}
+
+	effective, err := configuration.GetEffectiveAppConfiguration(root, appName, *environment)
+	if err != nil {
+		return err
+	}
+
+	_, err = os.Stdout.Write(effective)
+	return err
+}
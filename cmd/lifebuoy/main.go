@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+const usage = `Usage: lifebuoy <command> [arguments]
+
+Commands:
+  validate            Validate the configuration repository
+  config              Print the effective configuration of an app
+  plan                Print what the server would change in Docker
+  pause               Pause an app on a running server
+  resume              Resume an app on a running server
+  secrets keygen      Generate a key pair for app secrets
+  secrets public-key  Print the public key of a private key
+  secrets encrypt     Encrypt a secret read from stdin
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		exitWithUsage()
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "validate":
+		err = runValidate(os.Args[2:])
+	case "plan":
+		err = runPlan(os.Args[2:])
+	case "pause", "resume":
+		err = runPause(os.Args[1], os.Args[2:])
+	case "config":
+		err = runConfig(os.Args[2:])
+	case "secrets":
+		err = runSecrets(os.Args[2:])
+	default:
+		exitWithUsage()
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+func exitWithUsage() {
+	fmt.Fprint(os.Stderr, usage)
+	os.Exit(2)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// command is `pause` or `resume`
+func runPause(command string, args []string) error {
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	server := flags.String("server", "", "required: URL of the HTTP API of the server, e.g. http://localhost:8080")
+	apiToken := flags.String("apiToken", os.Getenv("LIFEBUOY_API_TOKEN"), "API token of the server. By default env LIFEBUOY_API_TOKEN")
+	reset := flags.Bool("reset", false, "Removes the override, so the configuration decides whether the app is paused")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: lifebuoy %s [flags] <app name>\n", command)
+		fmt.Fprintln(flags.Output(), "Overrides `enabled`/`paused` of the app on a running server. Containers of paused apps are stopped, but kept together with their volumes")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() < 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	if *server == "" {
+		return fmt.Errorf("Flag `server` is required")
+	}
+	if *apiToken == "" {
+		return fmt.Errorf("Flag `apiToken` is required")
+	}
+	appName := flags.Arg(0)
+
+	method := http.MethodPut
+	var body []byte
+	if *reset {
+		method = http.MethodDelete
+	} else {
+		body, _ = json.Marshal(map[string]bool{"paused": command == "pause"})
+	}
+
+	endpoint := fmt.Sprintf("%s/apps/%s/paused", strings.TrimSuffix(*server, "/"), url.PathEscape(appName))
+	request, err := http.NewRequest(method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", "Bearer "+*apiToken)
+	request.Header.Set("Content-Type", "application/json")
+
+	client := http.Client{Timeout: 30 * time.Second}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+
+	var result struct {
+		Paused bool   `json:"paused"`
+		Error  string `json:"error"`
+	}
+	if err := json.Unmarshal(responseBody, &result); err != nil {
+		return fmt.Errorf("Unexpected response with status %d: %s", response.StatusCode, strings.TrimSpace(string(responseBody)))
+	}
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("Server responded with status %d: %s", response.StatusCode, result.Error)
+	}
+
+	state := "running"
+	if result.Paused {
+		state = "paused"
+	}
+	fmt.Printf("App `%s` is %s\n", appName, state)
+	return nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/docker/docker/client"
+	"github.com/krystofrezac/lifebuoy/internal/configuration"
+	"github.com/krystofrezac/lifebuoy/internal/secrets"
+	"github.com/krystofrezac/lifebuoy/internal/sources"
+)
+
+func runPlan(args []string) error {
+	flags := flag.NewFlagSet("plan", flag.ExitOnError)
+	allowedBindMountPathsRaw := flags.String("allowedBindMountPaths", "", "Same as the server flag. Comma separated list of absolute host paths")
+	environment := flags.String("environment", "", "Same as the server flag. Name of the environment whose overlays are applied")
+	resourcePrefix := flags.String("resourcePrefix", "dev.lifebuoy.", "Same as the server flag. Prefix for docker resources")
+	secretsKeyFile := flags.String("secretsKeyFile", "", "Private key for decrypting secrets. Required when apps use secrets")
+	credentialsFile := flags.String("credentialsFile", "", "Same as the server flag. Required when apps reference credentials")
+	githubToken := flags.String("githubToken", "", "Same as the server flag. Token for Github repositories of apps without their own")
+	githubBaseUrl := flags.String("githubBaseUrl", "", "Same as the server flag. Github Enterprise API that 'githubToken' belongs to")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: lifebuoy plan [flags] [configuration root, default .]")
+		fmt.Fprintln(flags.Output(), "Prints what the server would change in Docker, without changing anything. Uses Docker from the environment")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	root := "."
+	if flags.NArg() > 0 {
+		root = flags.Arg(0)
+	}
+
+	opts := configuration.LocalPlanOpts{
+		Environment:    *environment,
+		ResourcePrefix: *resourcePrefix,
+	}
+	for _, allowedPath := range strings.Split(*allowedBindMountPathsRaw, ",") {
+		if allowedPath != "" {
+			opts.AllowedBindMountPaths = append(opts.AllowedBindMountPaths, allowedPath)
+		}
+	}
+	if *secretsKeyFile != "" {
+		key, err := readPrivateKey(*secretsKeyFile)
+		if err != nil {
+			return err
+		}
+		opts.SecretsKey = &key
+	}
+
+	opts.Credentials.Default = map[sources.ProviderType]sources.Credential{}
+	if *githubToken != "" {
+		opts.Credentials.Default[sources.ProviderGithub] = sources.Credential{
+			Token:   sources.NewStaticToken(secrets.NewValue(*githubToken)),
+			Type:    sources.ProviderGithub,
+			BaseUrl: *githubBaseUrl,
+		}
+	}
+	if *credentialsFile != "" {
+		named, err := sources.ReadCredentialsFile(*credentialsFile, secrets.NewRedactor())
+		if err != nil {
+			return err
+		}
+		opts.Credentials.Named = named
+	}
+
+	dockerClient, err := client.NewClientWithOpts(client.FromEnv)
+	if err != nil {
+		return err
+	}
+	defer dockerClient.Close()
+
+	// Configuration errors are logged
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	revisionPlan, err := configuration.PlanLocal(context.Background(), logger, dockerClient, root, opts)
+	if err != nil {
+		return err
+	}
+
+	fmt.Print(revisionPlan.Plan.String())
+
+	if len(revisionPlan.AppErrors) > 0 {
+		return fmt.Errorf("Found %d invalid apps, their current containers would be kept", len(revisionPlan.AppErrors))
+	}
+	return nil
+}
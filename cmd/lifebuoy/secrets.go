@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/krystofrezac/lifebuoy/internal/secrets"
+)
+
+func runSecrets(args []string) error {
+	if len(args) < 1 {
+		exitWithUsage()
+	}
+
+	switch args[0] {
+	case "keygen":
+		return runSecretsKeygen(args[1:])
+	case "public-key":
+		return runSecretsPublicKey(args[1:])
+	case "encrypt":
+		return runSecretsEncrypt(args[1:])
+	default:
+		exitWithUsage()
+		return nil
+	}
+}
+
+func runSecretsKeygen(args []string) error {
+	flags := flag.NewFlagSet("secrets keygen", flag.ExitOnError)
+	out := flags.String("out", "secrets.key", "Path where the private key will be written. Pass it to the server with '-secretsKeyFile'")
+	flags.Parse(args)
+
+	key, err := secrets.GenerateKey()
+	if err != nil {
+		return err
+	}
+
+	// O_EXCL, so an existing key (and secrets encrypted for it) isn't lost
+	file, err := os.OpenFile(*out, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = fmt.Fprintln(file, key.Encode())
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(key.PublicKey().Encode())
+	return nil
+}
+
+func runSecretsPublicKey(args []string) error {
+	flags := flag.NewFlagSet("secrets public-key", flag.ExitOnError)
+	keyFile := flags.String("keyFile", "secrets.key", "Path to the private key")
+	flags.Parse(args)
+
+	key, err := readPrivateKey(*keyFile)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(key.PublicKey().Encode())
+	return nil
+}
+
+func runSecretsEncrypt(args []string) error {
+	flags := flag.NewFlagSet("secrets encrypt", flag.ExitOnError)
+	publicKeyRaw := flags.String("publicKey", "", "required: Public key printed by 'secrets keygen'")
+	flags.Parse(args)
+
+	if *publicKeyRaw == "" {
+		return errors.New("Flag 'publicKey' is required")
+	}
+	publicKey, err := secrets.ParsePublicKey(*publicKeyRaw)
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return err
+	}
+
+	encrypted, err := publicKey.Encrypt(plaintext)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(encrypted)
+	return nil
+}
+
+func readPrivateKey(path string) (secrets.PrivateKey, error) {
+	encoded, err := os.ReadFile(path)
+	if err != nil {
+		return secrets.PrivateKey{}, err
+	}
+	return secrets.ParsePrivateKey(string(encoded))
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/krystofrezac/lifebuoy/internal/configuration"
+)
+
+func runValidate(args []string) error {
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+	allowedBindMountPathsRaw := flags.String("allowedBindMountPaths", "", "Same as the server flag. Comma separated list of absolute host paths")
+	environment := flags.String("environment", "", "Same as the server flag. Name of the environment whose overlays are applied")
+	secretsKeyFile := flags.String("secretsKeyFile", "", "Private key for checking that secrets can be decrypted. By default only their format is checked")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: lifebuoy validate [flags] [configuration root, default .]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	root := "."
+	if flags.NArg() > 0 {
+		root = flags.Arg(0)
+	}
+
+	opts := configuration.ValidationOpts{Environment: *environment}
+	for _, allowedPath := range strings.Split(*allowedBindMountPathsRaw, ",") {
+		if allowedPath != "" {
+			opts.AllowedBindMountPaths = append(opts.AllowedBindMountPaths, allowedPath)
+		}
+	}
+	if *secretsKeyFile != "" {
+		key, err := readPrivateKey(*secretsKeyFile)
+		if err != nil {
+			return err
+		}
+		opts.SecretsKey = &key
+	}
+
+	configurationErrors := configuration.Validate(root, opts)
+	if len(configurationErrors) == 0 {
+		fmt.Println("Configuration is valid")
+		return nil
+	}
+
+	for _, configurationError := range configurationErrors {
+		fmt.Fprintln(os.Stderr, configurationError.Error())
+	}
+	return fmt.Errorf("Found %d configuration errors", len(configurationErrors))
+}
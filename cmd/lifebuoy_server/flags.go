@@ -4,27 +4,81 @@ import (
 	"flag"
 	"log/slog"
 	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/krystofrezac/lifebuoy/internal/tarball"
 )
 
 type flags struct {
+	confSource             string
+	confLocalPath          string
+	confGitUrl             string
+	confGitRef             string
+	confGitSshKeyFile      *string
+	confGitKnownHostsFile  *string
 	confRepositoryOwner    string
 	confRepositoryName     string
 	confRepositoryRevision *string
-	githubToken            *string
-	logLevel               slog.Level
-	managedStoragePath     string
-	resourcePrefix         string
+	// Empty = the public instance of the provider
+	confRepositoryBaseUrl string
+	// nil = default Github credentials for the github source, anonymous access otherwise
+	confRepositoryToken *string
+	githubToken         *string
+	// Empty = github.com. Default Github credentials are sent only there
+	githubBaseUrl string
+	// Empty = Github App isn't used
+	githubAppId             string
+	githubAppInstallationId string
+	githubAppPrivateKeyFile string
+	logLevel                slog.Level
+	managedStoragePath      string
+	resourcePrefix          string
+	allowedBindMountPaths   []string
+	repositoryLimits        tarball.Limits
+	secretsKeyFile          *string
+	// nil = apps can't reference credentials
+	credentialsFile *string
+	// Empty = no environment overlays
+	environment string
+	// Empty = HTTP server is disabled
+	httpListenAddress string
+	// nil = webhooks are disabled
+	githubWebhookSecret *string
+	// nil = endpoints that require the token are disabled
+	apiToken *string
 }
 
 func loadFlags(logger *slog.Logger) flags {
-	confRepositoryOwner := flag.String("confRepositoryOwner", "", "required: Owner of Github repository used for configuration")
-	confRepositoryName := flag.String("confRepositoryName", "", "required: Name of Github repository used for configuration")
+	confSource := flag.String("confSource", "github", "Where the configuration is loaded from. One of: github, gitlab, gitea, local, git")
+	confLocalPath := flag.String("confLocalPath", "", "required for local source: Path to a directory with the configuration")
+	confGitUrl := flag.String("confGitUrl", "", "required for git source: URL of the git remote (ssh, https or file)")
+	confGitRef := flag.String("confGitRef", "HEAD", "Branch, tag or commit sha of the git source")
+	confGitSshKeyFile := flag.String("confGitSshKeyFile", "", "Private SSH key (e.g. deploy key) used by the git source. By default the SSH configuration of the user")
+	confGitKnownHostsFile := flag.String("confGitKnownHostsFile", "", "required with confGitSshKeyFile: known_hosts file with the host key of the git remote, other hosts are rejected. By default the SSH configuration of the user")
+	confRepositoryOwner := flag.String("confRepositoryOwner", "", "required for github, gitlab and gitea sources: Owner of the repository used for configuration")
+	confRepositoryName := flag.String("confRepositoryName", "", "required for github, gitlab and gitea sources: Name of the repository used for configuration")
 	confRepositoryRevision := flag.String("confRepositoryRevision", "", "Revision of the configuration repository. By default the default branch")
-	githubToken := flag.String("githubToken", "", "Token used for fetching repositories from Github")
+	confRepositoryBaseUrl := flag.String("confRepositoryBaseUrl", "", "required for gitea source: Base URL of the provider, e.g. https://gitea.example.com. By default the public instance")
+	confRepositoryToken := flag.String("confRepositoryToken", "", "Token used for fetching the configuration repository. By default 'githubToken' or the Github App for github source")
+	githubToken := flag.String("githubToken", "", "Token used for fetching repositories from Github, unless the app has its own")
+	githubBaseUrl := flag.String("githubBaseUrl", "", "API of Github Enterprise, e.g. https://github.example.com/api/v3. 'githubToken' and the Github App are used only for apps with this base URL. By default github.com")
+	githubAppId := flag.String("githubAppId", "", "ID of Github App used for fetching repositories from Github instead of 'githubToken'")
+	githubAppInstallationId := flag.String("githubAppInstallationId", "", "required with githubAppId: ID of the installation of the Github App")
+	githubAppPrivateKeyFile := flag.String("githubAppPrivateKeyFile", "", "required with githubAppId: Private key (PEM) of the Github App")
 
 	logLevelRaw := flag.String("logLevel", "INFO", "")
 	managedStoragePath := flag.String("managedStoragePath", "tmp", "Path to a directory where Lifebuoy will store data")
 	resourcePrefix := flag.String("resourcePrefix", "dev.lifebuoy.", "Prefix for docker resources(names/labels for images/containers)")
+	secretsKeyFile := flag.String("secretsKeyFile", "", "Private key used for decrypting app secrets. By default '<managedStoragePath>/secrets.key' if it exists")
+	credentialsFile := flag.String("credentialsFile", "", "YAML map of names to tokens that apps reference with 'credentials' in their source. By default '<managedStoragePath>/credentials.yaml' if it exists")
+	environment := flag.String("environment", "", "Name of the environment, e.g. staging. Overlays from 'environments/<name>/' in the configuration repository are applied over the apps")
+	httpListenAddress := flag.String("httpListenAddress", "", "Address of the HTTP API, e.g. ':8080'. By default the HTTP API is disabled")
+	githubWebhookSecret := flag.String("githubWebhookSecret", "", "Secret of Github webhooks. Push webhooks are accepted at '/webhooks/github' only when it's set")
+	apiToken := flag.String("apiToken", "", "Bearer token of the HTTP API. Endpoints other than webhooks are available only when it's set")
+	maxRepositorySize := flag.Int64("maxRepositorySize", tarball.DefaultMaxTotalSize, "Maximum size in bytes of a downloaded repository after extraction")
+	maxRepositoryFiles := flag.Int("maxRepositoryFiles", tarball.DefaultMaxFileCount, "Maximum number of files in a downloaded repository")
+	allowedBindMountPathsRaw := flag.String("allowedBindMountPaths", "", "Comma separated list of absolute host paths. Apps can bind mount only paths under them. By default nothing is allowed")
 
 	flag.Parse()
 
@@ -36,12 +90,32 @@ func loadFlags(logger *slog.Logger) flags {
 	}
 
 	// Checking required flags
-	if *confRepositoryOwner == "" {
-		logger.Error("Flag 'confRepositoryOwner' is required")
-		os.Exit(1)
-	}
-	if *confRepositoryName == "" {
-		logger.Error("Flag 'confRepositoryName' is required")
+	switch *confSource {
+	case "github", "gitlab", "gitea":
+		if *confRepositoryOwner == "" {
+			logger.Error("Flag 'confRepositoryOwner' is required")
+			os.Exit(1)
+		}
+		if *confRepositoryName == "" {
+			logger.Error("Flag 'confRepositoryName' is required")
+			os.Exit(1)
+		}
+		if *confSource == "gitea" && *confRepositoryBaseUrl == "" {
+			logger.Error("Flag 'confRepositoryBaseUrl' is required")
+			os.Exit(1)
+		}
+	case "local":
+		if *confLocalPath == "" {
+			logger.Error("Flag 'confLocalPath' is required")
+			os.Exit(1)
+		}
+	case "git":
+		if *confGitUrl == "" {
+			logger.Error("Flag 'confGitUrl' is required")
+			os.Exit(1)
+		}
+	default:
+		logger.Error("Flag 'confSource' has unknown value", "value", *confSource)
 		os.Exit(1)
 	}
 	if *managedStoragePath == "" {
@@ -49,6 +123,23 @@ func loadFlags(logger *slog.Logger) flags {
 		os.Exit(1)
 	}
 
+	if *maxRepositorySize <= 0 || *maxRepositoryFiles <= 0 {
+		logger.Error("Flags 'maxRepositorySize' and 'maxRepositoryFiles' have to be positive")
+		os.Exit(1)
+	}
+
+	var allowedBindMountPaths []string
+	for _, allowedPath := range strings.Split(*allowedBindMountPathsRaw, ",") {
+		if allowedPath == "" {
+			continue
+		}
+		if !filepath.IsAbs(allowedPath) {
+			logger.Error("Flag 'allowedBindMountPaths' can contain only absolute paths", "path", allowedPath)
+			os.Exit(1)
+		}
+		allowedBindMountPaths = append(allowedBindMountPaths, allowedPath)
+	}
+
 	// Nulling flags that weren't passed
 	if *confRepositoryRevision == "" {
 		confRepositoryRevision = nil
@@ -56,14 +147,86 @@ func loadFlags(logger *slog.Logger) flags {
 	if *githubToken == "" {
 		githubToken = nil
 	}
+	if *confRepositoryToken == "" {
+		confRepositoryToken = nil
+	}
+	if *githubAppId != "" {
+		if *githubAppInstallationId == "" || *githubAppPrivateKeyFile == "" {
+			logger.Error("Flag 'githubAppId' requires flags 'githubAppInstallationId' and 'githubAppPrivateKeyFile'")
+			os.Exit(1)
+		}
+		if githubToken != nil {
+			logger.Error("Flags 'githubAppId' and 'githubToken' can't be used together")
+			os.Exit(1)
+		}
+	}
+	if *confGitSshKeyFile == "" {
+		confGitSshKeyFile = nil
+	}
+	if *confGitKnownHostsFile == "" {
+		confGitKnownHostsFile = nil
+	}
+	if confGitSshKeyFile != nil && confGitKnownHostsFile == nil {
+		logger.Error("Flag 'confGitSshKeyFile' requires flag 'confGitKnownHostsFile'")
+		os.Exit(1)
+	}
+	if *githubWebhookSecret == "" {
+		githubWebhookSecret = nil
+	}
+	if githubWebhookSecret != nil && *httpListenAddress == "" {
+		logger.Error("Flag 'githubWebhookSecret' requires flag 'httpListenAddress'")
+		os.Exit(1)
+	}
+	if *apiToken == "" {
+		apiToken = nil
+	}
+	if apiToken != nil && *httpListenAddress == "" {
+		logger.Error("Flag 'apiToken' requires flag 'httpListenAddress'")
+		os.Exit(1)
+	}
+	if *secretsKeyFile == "" {
+		secretsKeyFile = nil
+		defaultSecretsKeyFile := filepath.Join(*managedStoragePath, "secrets.key")
+		if _, err := os.Stat(defaultSecretsKeyFile); err == nil {
+			secretsKeyFile = &defaultSecretsKeyFile
+		}
+	}
+
+	if *credentialsFile == "" {
+		credentialsFile = nil
+		defaultCredentialsFile := filepath.Join(*managedStoragePath, "credentials.yaml")
+		if _, err := os.Stat(defaultCredentialsFile); err == nil {
+			credentialsFile = &defaultCredentialsFile
+		}
+	}
 
 	return flags{
-		confRepositoryOwner:    *confRepositoryOwner,
-		confRepositoryName:     *confRepositoryName,
-		confRepositoryRevision: confRepositoryRevision,
-		githubToken:            githubToken,
-		logLevel:               logLevel.Level(),
-		managedStoragePath:     *managedStoragePath,
-		resourcePrefix:         *resourcePrefix,
+		confSource:              *confSource,
+		confLocalPath:           *confLocalPath,
+		confGitUrl:              *confGitUrl,
+		confGitRef:              *confGitRef,
+		confGitSshKeyFile:       confGitSshKeyFile,
+		confGitKnownHostsFile:   confGitKnownHostsFile,
+		confRepositoryOwner:     *confRepositoryOwner,
+		confRepositoryName:      *confRepositoryName,
+		confRepositoryRevision:  confRepositoryRevision,
+		confRepositoryBaseUrl:   *confRepositoryBaseUrl,
+		confRepositoryToken:     confRepositoryToken,
+		githubToken:             githubToken,
+		githubBaseUrl:           *githubBaseUrl,
+		githubAppId:             *githubAppId,
+		githubAppInstallationId: *githubAppInstallationId,
+		githubAppPrivateKeyFile: *githubAppPrivateKeyFile,
+		logLevel:                logLevel.Level(),
+		managedStoragePath:      *managedStoragePath,
+		resourcePrefix:          *resourcePrefix,
+		allowedBindMountPaths:   allowedBindMountPaths,
+		repositoryLimits:        tarball.Limits{MaxTotalSize: *maxRepositorySize, MaxFileCount: *maxRepositoryFiles},
+		secretsKeyFile:          secretsKeyFile,
+		credentialsFile:         credentialsFile,
+		environment:             *environment,
+		httpListenAddress:       *httpListenAddress,
+		githubWebhookSecret:     githubWebhookSecret,
+		apiToken:                apiToken,
 	}
 }
@@ -6,23 +6,47 @@ import (
 	"os"
 
 	"github.com/docker/docker/client"
+	"github.com/krystofrezac/lifebuoy/internal/api"
 	"github.com/krystofrezac/lifebuoy/internal/apps"
 	"github.com/krystofrezac/lifebuoy/internal/configuration"
 	"github.com/krystofrezac/lifebuoy/internal/container_manager"
 	"github.com/krystofrezac/lifebuoy/internal/docker"
+	"github.com/krystofrezac/lifebuoy/internal/secrets"
+	"github.com/krystofrezac/lifebuoy/internal/sources"
 )
 
 func main() {
 	ctx := context.Background()
 
 	logLevel := new(slog.LevelVar)
+	redactor := secrets.NewRedactor()
 	logger := slog.New(
-		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: logLevel}),
+		secrets.NewRedactingHandler(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: logLevel}),
+			redactor,
+		),
 	)
 
 	flags := loadFlags(logger)
 	logLevel.Set(flags.logLevel)
 
+	var secretsKey *secrets.PrivateKey
+	if flags.secretsKeyFile != nil {
+		encodedKey, err := os.ReadFile(*flags.secretsKeyFile)
+		if err != nil {
+			logger.Error("Failed to read secrets key", "err", err)
+			os.Exit(1)
+		}
+		key, err := secrets.ParsePrivateKey(string(encodedKey))
+		if err != nil {
+			logger.Error("Failed to parse secrets key", "err", err)
+			os.Exit(1)
+		}
+		secretsKey = &key
+	}
+
+	credentials := getCredentials(logger, flags, redactor)
+
 	dockerClient, err := client.NewClientWithOpts(client.FromEnv)
 	if err != nil {
 		logger.Error("Failed to initialize docker client", "err", err)
@@ -43,23 +67,122 @@ func main() {
 		dockerConf,
 		flags.managedStoragePath,
 		flags.resourcePrefix,
+		flags.repositoryLimits,
 	)
 	dockefileAppCreator := apps.NewDockefileAppCreator(logger, dockerClient)
 	configurationManager := configuration.NewConfigurationManager(
 		logger,
-		flags.confRepositoryOwner,
-		flags.confRepositoryName,
-		flags.confRepositoryRevision,
-		flags.githubToken,
+		getConfigurationSource(logger, flags, credentials),
 		flags.managedStoragePath,
+		flags.environment,
+		flags.allowedBindMountPaths,
+		secretsKey,
+		credentials,
+		redactor,
 		flags.resourcePrefix,
 		repositoryBuildAppCreator,
 		dockefileAppCreator,
 		containerManagerInstance,
+		flags.githubWebhookSecret != nil,
 	)
 
 	go containerManagerInstance.Start(ctx)
 	go configurationManager.Start(ctx)
 
+	if flags.httpListenAddress != "" {
+		var githubWebhookSecret []byte
+		if flags.githubWebhookSecret != nil {
+			githubWebhookSecret = []byte(*flags.githubWebhookSecret)
+		}
+
+		var apiToken []byte
+		if flags.apiToken != nil {
+			apiToken = []byte(*flags.apiToken)
+		}
+
+		apiServer := api.NewServer(logger, flags.httpListenAddress, configurationManager, githubWebhookSecret, apiToken)
+		go func() {
+			err := apiServer.Start(ctx)
+			if err != nil {
+				logger.Error("HTTP server failed", "err", err)
+				os.Exit(1)
+			}
+		}()
+	}
+
 	select {}
 }
+
+func getCredentials(logger *slog.Logger, flags flags, redactor *secrets.Redactor) sources.Credentials {
+	credentials := sources.Credentials{Default: map[sources.ProviderType]sources.Credential{}}
+	if flags.githubToken != nil {
+		token := secrets.NewValue(*flags.githubToken)
+		redactor.Register(token)
+		credentials.Default[sources.ProviderGithub] = sources.Credential{Token: sources.NewStaticToken(token), Type: sources.ProviderGithub, BaseUrl: flags.githubBaseUrl}
+	}
+	if flags.githubAppId != "" {
+		privateKey, err := os.ReadFile(flags.githubAppPrivateKeyFile)
+		if err != nil {
+			logger.Error("Failed to read private key of Github App", "err", err)
+			os.Exit(1)
+		}
+		tokenSource, err := sources.NewGithubAppTokenSource(logger, redactor, flags.githubBaseUrl, flags.githubAppId, flags.githubAppInstallationId, privateKey)
+		if err != nil {
+			logger.Error("Failed to initialize Github App", "err", err)
+			os.Exit(1)
+		}
+		credentials.Default[sources.ProviderGithub] = sources.Credential{Token: tokenSource, Type: sources.ProviderGithub, BaseUrl: flags.githubBaseUrl}
+	}
+
+	if flags.credentialsFile != nil {
+		named, err := sources.ReadCredentialsFile(*flags.credentialsFile, redactor)
+		if err != nil {
+			logger.Error("Failed to read credentials", "err", err)
+			os.Exit(1)
+		}
+		credentials.Named = named
+	}
+
+	return credentials
+}
+
+func getConfigurationSource(logger *slog.Logger, flags flags, credentials sources.Credentials) configuration.Source {
+	switch flags.confSource {
+	case "local":
+		return configuration.NewLocalSource(flags.confLocalPath)
+	case "git":
+		return configuration.NewGitSource(
+			flags.confGitUrl,
+			flags.confGitRef,
+			flags.confGitSshKeyFile,
+			flags.confGitKnownHostsFile,
+			flags.managedStoragePath,
+		)
+	}
+
+	providerOpts := sources.ProviderOpts{
+		Type:    sources.ProviderType(flags.confSource),
+		BaseUrl: flags.confRepositoryBaseUrl,
+		Limits:  flags.repositoryLimits,
+	}
+	if flags.confRepositoryToken != nil {
+		providerOpts.Token = sources.NewStaticToken(secrets.NewValue(*flags.confRepositoryToken))
+	} else {
+		// Default credentials don't belong to other instances
+		providerOpts.Token, _ = credentials.GetToken(providerOpts.Type, providerOpts.BaseUrl, "")
+	}
+
+	source, err := configuration.NewRepositorySource(
+		logger,
+		providerOpts,
+		flags.confRepositoryOwner,
+		flags.confRepositoryName,
+		flags.confRepositoryRevision,
+		flags.managedStoragePath,
+	)
+	if err != nil {
+		logger.Error("Failed to initialize configuration source", "err", err)
+		os.Exit(1)
+	}
+	return source
+}
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+type appPausedRequest struct {
+	Paused *bool `json:"paused"`
+}
+
+type appPausedResponse struct {
+	AppName string `json:"appName"`
+	// Effective state, after the override is applied
+	Paused bool `json:"paused"`
+}
+
+// Body `{"paused": true}` pauses the app, `{"paused": false}` keeps it running even when the configuration pauses it
+func (s *Server) handleSetAppPaused(w http.ResponseWriter, r *http.Request) {
+	var body appPausedRequest
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil || body.Paused == nil {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": "Body has to be `{\"paused\": <bool>}`"})
+		return
+	}
+
+	s.setAppPaused(w, r, body.Paused)
+}
+
+// The configuration decides whether the app is paused again
+func (s *Server) handleDeleteAppPaused(w http.ResponseWriter, r *http.Request) {
+	s.setAppPaused(w, r, nil)
+}
+
+func (s *Server) setAppPaused(w http.ResponseWriter, r *http.Request, paused *bool) {
+	appName := r.PathValue("appName")
+	effectivePaused, err := s.configurationManager.SetAppPaused(r.Context(), appName, paused)
+	if err != nil {
+		s.logger.Error("Failed to change pause override", "appName", appName, "err", err)
+		writeJson(w, http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	}
+
+	writeJson(w, http.StatusOK, appPausedResponse{AppName: appName, Paused: effectivePaused})
+}
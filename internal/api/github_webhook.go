@@ -0,0 +1,93 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/krystofrezac/lifebuoy/internal/configuration"
+)
+
+// Github sends payloads up to 25 MB
+const maxGithubWebhookBodySize = 25 << 20
+
+type githubPushEvent struct {
+	Ref        string `json:"ref"`
+	Repository struct {
+		Name  string `json:"name"`
+		Owner struct {
+			Login string `json:"login"`
+			// Older payloads have only the name
+			Name string `json:"name"`
+		} `json:"owner"`
+	} `json:"repository"`
+}
+
+func (s *Server) handleGithubWebhook(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxGithubWebhookBodySize))
+	if err != nil {
+		http.Error(w, "Failed to read body", http.StatusBadRequest)
+		return
+	}
+
+	if !isGithubSignatureValid(s.githubWebhookSecret, body, r.Header.Get("X-Hub-Signature-256")) {
+		s.logger.Warn("Github webhook with invalid signature", "remoteAddr", r.RemoteAddr)
+		http.Error(w, "Invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	// Other events (e.g. `ping` after the webhook is created) are acknowledged, but ignored
+	if r.Header.Get("X-GitHub-Event") != "push" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	push, err := parseGithubPushEvent(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.logger.Info("Received Github push", "owner", push.Owner, "repository", push.Repository, "ref", push.Ref)
+	s.configurationManager.HandleGithubPush(push)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func parseGithubPushEvent(body []byte) (configuration.GithubPush, error) {
+	event := githubPushEvent{}
+	err := json.Unmarshal(body, &event)
+	if err != nil {
+		return configuration.GithubPush{}, err
+	}
+
+	owner := event.Repository.Owner.Login
+	if owner == "" {
+		owner = event.Repository.Owner.Name
+	}
+
+	return configuration.GithubPush{
+		Owner:      owner,
+		Repository: event.Repository.Name,
+		Ref:        event.Ref,
+	}, nil
+}
+
+// signature is the value of the `X-Hub-Signature-256` header
+func isGithubSignatureValid(secret []byte, body []byte, signature string) bool {
+	hexDigest, ok := strings.CutPrefix(signature, "sha256=")
+	if !ok {
+		return false
+	}
+	digest, err := hex.DecodeString(hexDigest)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), digest)
+}
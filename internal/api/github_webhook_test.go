@@ -0,0 +1,42 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/krystofrezac/lifebuoy/internal/configuration"
+)
+
+func TestIsGithubSignatureValid(t *testing.T) {
+	// Example from the Github documentation
+	secret := []byte("It's a Secret to Everybody")
+	body := []byte("Hello, World!")
+
+	tests := []struct {
+		signature string
+		expected  bool
+	}{
+		{"sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17", true},
+		{"sha256=857107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17", false},
+		{"757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17", false},
+		{"sha256=not-hex", false},
+		{"", false},
+	}
+
+	for _, test := range tests {
+		if isGithubSignatureValid(secret, body, test.signature) != test.expected {
+			t.Fatalf("Expected %v for signature '%s'", test.expected, test.signature)
+		}
+	}
+}
+
+func TestParseGithubPushEvent(t *testing.T) {
+	push, err := parseGithubPushEvent([]byte(`{"ref": "refs/heads/main", "repository": {"name": "app", "owner": {"login": "acme"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := configuration.GithubPush{Owner: "acme", Repository: "app", Ref: "refs/heads/main"}
+	if push != expected {
+		t.Fatalf("Expected %+v, got %+v", expected, push)
+	}
+}
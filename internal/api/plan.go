@@ -0,0 +1,28 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/krystofrezac/lifebuoy/internal/configuration"
+)
+
+// Query parameter `revision` selects the configuration revision, by default the latest one
+func (s *Server) handlePlan(w http.ResponseWriter, r *http.Request) {
+	revision := r.URL.Query().Get("revision")
+	if revision != "" {
+		err := configuration.ValidateRevision(revision)
+		if err != nil {
+			writeJson(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+	}
+
+	plan, err := s.configurationManager.Plan(r.Context(), revision)
+	if err != nil {
+		s.logger.Error("Failed to plan configuration", "err", err)
+		writeJson(w, http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	}
+
+	writeJson(w, http.StatusOK, plan)
+}
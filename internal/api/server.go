@@ -0,0 +1,100 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/krystofrezac/lifebuoy/internal/configuration"
+	"github.com/krystofrezac/lifebuoy/internal/sources"
+)
+
+// HTTP API of the server
+type Server struct {
+	logger               *slog.Logger
+	configurationManager *configuration.ConfigurationManager
+	// nil = webhooks are disabled
+	githubWebhookSecret []byte
+	// nil = endpoints that require the token are disabled
+	apiToken   []byte
+	httpServer *http.Server
+}
+
+func NewServer(
+	logger *slog.Logger,
+	listenAddress string,
+	configurationManager *configuration.ConfigurationManager,
+	githubWebhookSecret []byte,
+	apiToken []byte,
+) *Server {
+	s := &Server{
+		logger:               logger,
+		configurationManager: configurationManager,
+		githubWebhookSecret:  githubWebhookSecret,
+		apiToken:             apiToken,
+	}
+
+	mux := http.NewServeMux()
+	if githubWebhookSecret != nil {
+		mux.HandleFunc("POST /webhooks/github", s.handleGithubWebhook)
+	}
+	if apiToken != nil {
+		mux.HandleFunc("GET /plan", s.requireApiToken(s.handlePlan))
+		mux.HandleFunc("GET /debug/vars", s.requireApiToken(s.handleMetrics))
+		mux.HandleFunc("PUT /apps/{appName}/paused", s.requireApiToken(s.handleSetAppPaused))
+		mux.HandleFunc("DELETE /apps/{appName}/paused", s.requireApiToken(s.handleDeleteAppPaused))
+	}
+
+	s.httpServer = &http.Server{
+		Addr:              listenAddress,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	return s
+}
+
+// Requests have to contain header `Authorization: Bearer <api token>`
+func (s *Server) requireApiToken(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), s.apiToken) != 1 {
+			http.Error(w, "Invalid API token", http.StatusUnauthorized)
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// Metrics, e.g. the state of the Github rate limit. Global variables of expvar aren't served, `cmdline` contains tokens from flags
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	writeJson(w, http.StatusOK, map[string]json.RawMessage{"github": json.RawMessage(sources.GithubMetrics())})
+}
+
+func writeJson(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+// Blocks until the context is cancelled or the server fails
+func (s *Server) Start(ctx context.Context) error {
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = s.httpServer.Shutdown(shutdownCtx)
+	}()
+
+	s.logger.Info("Starting HTTP server", "address", s.httpServer.Addr)
+	err := s.httpServer.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
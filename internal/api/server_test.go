@@ -0,0 +1,62 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandlePlan_RejectsInvalidRevision(t *testing.T) {
+	s := &Server{}
+	request := httptest.NewRequest(http.MethodGet, "/plan?revision=--upload-pack%3Dtouch", nil)
+	recorder := httptest.NewRecorder()
+
+	s.handlePlan(recorder, request)
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status %d, got %d", http.StatusBadRequest, recorder.Code)
+	}
+}
+
+func TestRequireApiToken(t *testing.T) {
+	s := &Server{apiToken: []byte("token")}
+	handler := s.requireApiToken(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		authorization string
+		expected      int
+	}{
+		{"Bearer token", http.StatusNoContent},
+		{"Bearer other", http.StatusUnauthorized},
+		{"token", http.StatusUnauthorized},
+		{"", http.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		request := httptest.NewRequest(http.MethodGet, "/plan", nil)
+		request.Header.Set("Authorization", test.authorization)
+		recorder := httptest.NewRecorder()
+
+		handler(recorder, request)
+		if recorder.Code != test.expected {
+			t.Fatalf("Expected status %d for '%s', got %d", test.expected, test.authorization, recorder.Code)
+		}
+	}
+}
+
+func TestHandleMetrics_DoesNotExposeCommandLine(t *testing.T) {
+	s := &Server{}
+	request := httptest.NewRequest(http.MethodGet, "/debug/vars", nil)
+	recorder := httptest.NewRecorder()
+
+	s.handleMetrics(recorder, request)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, recorder.Code)
+	}
+	body := recorder.Body.String()
+	if !strings.Contains(body, `"github"`) || strings.Contains(body, "cmdline") {
+		t.Fatalf("Expected only Github metrics, got %s", body)
+	}
+}
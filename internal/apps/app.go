@@ -1,16 +1,77 @@
 package apps
 
-import "context"
+import (
+	"context"
+	"time"
+
+	"github.com/krystofrezac/lifebuoy/internal/secrets"
+)
 
 // TODO: duplicated in container_manager
 const managedLabel = "dev.lifebuoy.managed"
 const appNameLabel = "dev.lifebuoy.app-name"
 
+// Name of the Traefik entrypoint that serves app routes
+const TraefikEntrypoint = "web"
+
+type Route struct {
+	Port uint16
+	Host string
+	// Always starts with `/`
+	PathPrefix string
+}
+
+type VolumeType string
+
+const (
+	// Docker volume created and owned by Lifebuoy
+	VolumeTypeManaged VolumeType = "managed"
+	// Bind mount of a host path
+	VolumeTypeManual VolumeType = "manual"
+)
+
+type Volume struct {
+	Type VolumeType
+	// Only for managed volumes. Name unique within the app, the Docker volume name is derived from it
+	Name string
+	// Only for manual volumes. Path on the host
+	From string
+	// Path inside of the container
+	To       string
+	ReadOnly bool
+}
+
+// Command run inside of the container to find out whether the app is healthy
+type Healthcheck struct {
+	// Run by the shell of the container
+	Command     string
+	Interval    time.Duration
+	Timeout     time.Duration
+	Retries     int
+	StartPeriod time.Duration
+}
+
 type AppConfiguration struct {
 	// TODO: does it make sense to have 3 different names? AppName and Image probably yeah, beacause we may have the same app in multiple instances
 	AppName string
 	Image   string
-	Volumes map[string]struct{}
+	Volumes []Volume
+	Routes  []Route
+	Env     map[string]string
+	// Environment variables with decrypted values
+	SecretEnv map[string]secrets.Value
+	// Files with decrypted content. Key is the path inside of the container
+	SecretFiles map[string]secrets.Value
+	// Host paths mounted into the container. In standard Docker format <from>:<to>[:<options>]
+	Binds []string
+	// Ports published on the host. In standard Docker format [<ip>:]<hostPort>:<containerPort>
+	PortMappings []string
+	// Names of apps that have to be running (and healthy when they have a healthcheck) before this app is started
+	DependsOn []string
+	// nil = health of the app isn't checked
+	Healthcheck *Healthcheck
+	// Number of identical containers, routes are load balanced between them. 0 = 1
+	Replicas int
 }
 
 type App interface {
@@ -19,4 +80,9 @@ type App interface {
 	// Be prepared that this function can be called multiple times
 	Build(context.Context) error
 	Configuration() AppConfiguration
+	// Returns the app pinned to the current state of its moving parts, e.g. branch resolved to a commit sha.
+	// Other methods can be called on unresolved app, but they describe the configured state
+	Resolve(context.Context) (App, error)
+	// Normalized inputs the app was created from. Used to find what changed between configuration revisions
+	Definition() any
 }
@@ -0,0 +1,179 @@
+package apps
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/krystofrezac/lifebuoy/internal/secrets"
+)
+
+type AppChange struct {
+	AppName string
+	// Paths of the changed fields of the app definition, e.g. `env.DATABASE_URL` or `routes[0].host`. Sorted
+	ChangedFields []string
+}
+
+// Difference between two sets of apps. All lists are sorted by app name
+type Changeset struct {
+	Added    []string
+	Removed  []string
+	Modified []AppChange
+}
+
+func (c Changeset) IsEmpty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0 && len(c.Modified) == 0
+}
+
+// Names of the apps that were added or modified
+func (c Changeset) AffectedApps() []string {
+	res := make([]string, 0, len(c.Added)+len(c.Modified))
+	res = append(res, c.Added...)
+	for _, change := range c.Modified {
+		res = append(res, change.AppName)
+	}
+	slices.Sort(res)
+	return res
+}
+
+// Human readable summary. Values are never included, so it's safe to log even when secrets changed
+func (c Changeset) String() string {
+	if c.IsEmpty() {
+		return "no changes"
+	}
+
+	var parts []string
+	if len(c.Added) > 0 {
+		parts = append(parts, "added: "+strings.Join(c.Added, ", "))
+	}
+	if len(c.Removed) > 0 {
+		parts = append(parts, "removed: "+strings.Join(c.Removed, ", "))
+	}
+	if len(c.Modified) > 0 {
+		modified := make([]string, 0, len(c.Modified))
+		for _, change := range c.Modified {
+			modified = append(modified, fmt.Sprintf("%s (%s)", change.AppName, strings.Join(change.ChangedFields, ", ")))
+		}
+		parts = append(parts, "modified: "+strings.Join(modified, ", "))
+	}
+
+	return strings.Join(parts, "; ")
+}
+
+// Compares apps by their name and definition. Order of the apps doesn't matter
+func Diff(oldApps []App, newApps []App) Changeset {
+	oldDefinitions := getFlatDefinitions(oldApps)
+	newDefinitions := getFlatDefinitions(newApps)
+
+	changeset := Changeset{}
+	for appName, newDefinition := range newDefinitions {
+		oldDefinition, ok := oldDefinitions[appName]
+		if !ok {
+			changeset.Added = append(changeset.Added, appName)
+			continue
+		}
+
+		changedFields := getChangedFields(oldDefinition, newDefinition)
+		if len(changedFields) > 0 {
+			changeset.Modified = append(changeset.Modified, AppChange{AppName: appName, ChangedFields: changedFields})
+		}
+	}
+	for appName := range oldDefinitions {
+		if _, ok := newDefinitions[appName]; !ok {
+			changeset.Removed = append(changeset.Removed, appName)
+		}
+	}
+
+	slices.Sort(changeset.Added)
+	slices.Sort(changeset.Removed)
+	slices.SortFunc(changeset.Modified, func(a AppChange, b AppChange) int {
+		return strings.Compare(a.AppName, b.AppName)
+	})
+
+	return changeset
+}
+
+func getChangedFields(oldDefinition map[string]string, newDefinition map[string]string) []string {
+	var res []string
+	for field, newValue := range newDefinition {
+		if oldValue, ok := oldDefinition[field]; !ok || oldValue != newValue {
+			res = append(res, field)
+		}
+	}
+	for field := range oldDefinition {
+		if _, ok := newDefinition[field]; !ok {
+			res = append(res, field)
+		}
+	}
+
+	slices.Sort(res)
+	return res
+}
+
+// Key is the app name, value is the flattened definition
+func getFlatDefinitions(apps []App) map[string]map[string]string {
+	res := make(map[string]map[string]string, len(apps))
+	for _, app := range apps {
+		flat := map[string]string{}
+		flatten("", reflect.ValueOf(app.Definition()), flat)
+		res[app.Configuration().AppName] = flat
+	}
+	return res
+}
+
+var secretValueType = reflect.TypeOf(secrets.Value{})
+
+// Turns nested value into map of field paths and their values. Secrets are replaced with their hash
+func flatten(prefix string, value reflect.Value, res map[string]string) {
+	if !value.IsValid() {
+		return
+	}
+
+	if value.Type() == secretValueType {
+		secret := value.Interface().(secrets.Value)
+		res[prefix] = fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(secret.Reveal())))
+		return
+	}
+
+	switch value.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if value.IsNil() {
+			return
+		}
+		flatten(prefix, value.Elem(), res)
+	case reflect.Struct:
+		for i := range value.NumField() {
+			field := value.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			flatten(joinFieldPath(prefix, lowerFirst(field.Name)), value.Field(i), res)
+		}
+	case reflect.Map:
+		for _, key := range value.MapKeys() {
+			flatten(joinFieldPath(prefix, fmt.Sprint(key.Interface())), value.MapIndex(key), res)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := range value.Len() {
+			flatten(fmt.Sprintf("%s[%d]", prefix, i), value.Index(i), res)
+		}
+	default:
+		res[prefix] = fmt.Sprint(value.Interface())
+	}
+}
+
+func joinFieldPath(prefix string, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+func lowerFirst(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToLower(r)) + s[size:]
+}
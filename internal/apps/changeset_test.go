@@ -0,0 +1,59 @@
+package apps
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	creator := RepositoryBuildAppCreator{}
+	oldApps := []App{
+		creator.Create(RepositoryBuildAppCreateOpts{AppName: "kept", RepositoryRevision: "v1"}),
+		creator.Create(RepositoryBuildAppCreateOpts{
+			AppName:            "modified",
+			RepositoryRevision: "v1",
+			Env:                map[string]string{"A": "1", "B": "1"},
+			Routes:             []Route{{Port: 80, Host: "example.com", PathPrefix: "/"}},
+		}),
+		creator.Create(RepositoryBuildAppCreateOpts{AppName: "removed"}),
+	}
+	newApps := []App{
+		creator.Create(RepositoryBuildAppCreateOpts{AppName: "added"}),
+		creator.Create(RepositoryBuildAppCreateOpts{
+			AppName:            "modified",
+			RepositoryRevision: "v2",
+			Env:                map[string]string{"A": "1", "C": "1"},
+			Routes:             []Route{{Port: 80, Host: "example.org", PathPrefix: "/"}},
+		}),
+		creator.Create(RepositoryBuildAppCreateOpts{AppName: "kept", RepositoryRevision: "v1"}),
+	}
+
+	expected := Changeset{
+		Added:   []string{"added"},
+		Removed: []string{"removed"},
+		Modified: []AppChange{{
+			AppName:       "modified",
+			ChangedFields: []string{"env.B", "env.C", "repositoryRevision", "routes[0].host"},
+		}},
+	}
+
+	res := Diff(oldApps, newApps)
+	if !reflect.DeepEqual(res, expected) {
+		t.Fatalf("Expected %+v, got %+v", expected, res)
+	}
+
+	expectedSummary := "added: added; removed: removed; modified: modified (env.B, env.C, repositoryRevision, routes[0].host)"
+	if res.String() != expectedSummary {
+		t.Fatalf("Expected '%s', got '%s'", expectedSummary, res.String())
+	}
+}
+
+func TestDiff_NoChanges(t *testing.T) {
+	creator := DockerFileAppCreator{}
+	apps := []App{creator.Create(DockefileAppCreateOpts{AppName: "app", Binds: []string{"/a:/b"}})}
+
+	res := Diff(apps, apps)
+	if !res.IsEmpty() {
+		t.Fatalf("Expected no changes, got %+v", res)
+	}
+}
@@ -24,6 +24,11 @@ type DockerFileAppCreator struct {
 type DockefileAppCreateOpts struct {
 	AppName    string
 	Dockerfile string
+	Volumes    []Volume
+	// See [AppConfiguration.Binds]
+	Binds []string
+	// See [AppConfiguration.PortMappings]
+	PortMappings []string
 }
 
 func NewDockefileAppCreator(
@@ -127,12 +132,23 @@ func (d DockeFileApp) Build(ctx context.Context) error {
 
 func (d DockeFileApp) Configuration() AppConfiguration {
 	return AppConfiguration{
-		AppName: d.AppName,
-		Image:   d.getImage(),
-		// TODO: volumes
+		AppName:      d.AppName,
+		Image:        d.getImage(),
+		Volumes:      d.Volumes,
+		Binds:        d.Binds,
+		PortMappings: d.PortMappings,
 	}
 }
 
+// Dockerfile is part of the app, so there is nothing to resolve
+func (d DockeFileApp) Resolve(ctx context.Context) (App, error) {
+	return d, nil
+}
+
+func (d DockeFileApp) Definition() any {
+	return d.DockefileAppCreateOpts
+}
+
 func (d DockeFileApp) getImage() string {
 	sha := fmt.Sprintf("%x", sha256.Sum256([]byte(d.Dockerfile)))
 	return fmt.Sprintf("%s:%s", d.AppName, sha)
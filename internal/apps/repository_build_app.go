@@ -2,16 +2,20 @@ package apps
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"path"
+	"time"
 
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/client"
 	"github.com/krystofrezac/lifebuoy/internal/docker"
-	"github.com/krystofrezac/lifebuoy/internal/github"
+	"github.com/krystofrezac/lifebuoy/internal/secrets"
+	"github.com/krystofrezac/lifebuoy/internal/sources"
+	"github.com/krystofrezac/lifebuoy/internal/tarball"
 )
 
 // Creator
@@ -21,13 +25,33 @@ type RepositoryBuildAppCreator struct {
 	customDockerClient docker.Docker
 	managedStoragePath string
 	resourcePrefix     string
+	// Of downloaded repositories of all apps
+	repositoryLimits tarball.Limits
+	revisionResolver *revisionResolver
 }
 
 type RepositoryBuildAppCreateOpts struct {
-	AppName            string
+	AppName string
+	// Where the repository is hosted
+	RepositoryProvider sources.ProviderOpts
 	RepositoryOwner    string
 	RepositoryName     string
 	RepositoryRevision string
+	// Relative to the repository root
+	DockefileLocation string
+	// Relative to the repository root
+	BuildContext string
+	// Covers downloading of the repository and the image build
+	BuildTimeout time.Duration
+	Routes       []Route
+	Volumes      []Volume
+	Env          map[string]string
+	SecretEnv    map[string]secrets.Value
+	SecretFiles  map[string]secrets.Value
+	DependsOn    []string
+	// nil = health of the app isn't checked
+	Healthcheck *Healthcheck
+	Replicas    int
 }
 
 func NewRepositoryBuilderAppCreator(
@@ -36,6 +60,7 @@ func NewRepositoryBuilderAppCreator(
 	customDockerClient docker.Docker,
 	managedStoragePath string,
 	resourcePrefix string,
+	repositoryLimits tarball.Limits,
 ) RepositoryBuildAppCreator {
 	return RepositoryBuildAppCreator{
 		logger:             logger,
@@ -43,10 +68,18 @@ func NewRepositoryBuilderAppCreator(
 		dockerClient:       dockerClient,
 		managedStoragePath: managedStoragePath,
 		resourcePrefix:     resourcePrefix,
+		repositoryLimits:   repositoryLimits,
+		revisionResolver:   newRevisionResolver(logger),
 	}
 }
 
+// Apps from the repository resolve their revisions again on the next [App.Resolve]
+func (r RepositoryBuildAppCreator) InvalidateRevisions(providerOpts sources.ProviderOpts, owner string, repository string) {
+	r.revisionResolver.invalidate(providerOpts, owner, repository)
+}
+
 func (r RepositoryBuildAppCreator) Create(opts RepositoryBuildAppCreateOpts) App {
+	opts.RepositoryProvider.Limits = r.repositoryLimits
 	return repositoryBuildApp{
 		RepositoryBuildAppCreator:    r,
 		RepositoryBuildAppCreateOpts: opts,
@@ -57,6 +90,19 @@ func (r RepositoryBuildAppCreator) Create(opts RepositoryBuildAppCreateOpts) App
 type repositoryBuildApp struct {
 	RepositoryBuildAppCreator
 	RepositoryBuildAppCreateOpts
+	// Empty = not resolved yet
+	resolvedSha string
+}
+
+// Branches and tags are resolved to the commit sha, so the image changes when they move
+func (r repositoryBuildApp) Resolve(ctx context.Context) (App, error) {
+	sha, err := r.revisionResolver.resolve(ctx, r.RepositoryProvider, r.RepositoryOwner, r.RepositoryName, r.RepositoryRevision)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to resolve revision `%s` of app `%s`: %w", r.RepositoryRevision, r.AppName, err)
+	}
+
+	r.resolvedSha = sha
+	return r, nil
 }
 
 func (r repositoryBuildApp) IsBuilt(ctx context.Context) bool {
@@ -87,7 +133,11 @@ func (r repositoryBuildApp) IsBuilt(ctx context.Context) bool {
 }
 
 func (r repositoryBuildApp) Build(ctx context.Context) error {
-	buildDir := path.Join(r.managedStoragePath, "/build")
+	// Every app has its own directory, so builds can run in parallel
+	buildDir := path.Join(r.managedStoragePath, "build", r.AppName)
+
+	ctx, cancel := context.WithTimeout(ctx, r.BuildTimeout)
+	defer cancel()
 
 	defer func() {
 		removeErr := os.RemoveAll(buildDir)
@@ -96,27 +146,62 @@ func (r repositoryBuildApp) Build(ctx context.Context) error {
 		}
 	}()
 
-	err := github.DownloadRepository(context.Background(), r.RepositoryOwner, r.RepositoryName, &r.RepositoryRevision, nil, buildDir)
+	provider, err := sources.NewProvider(r.logger, r.RepositoryProvider)
 	if err != nil {
 		return err
 	}
 
-	r.logger.Info("Starting to build image")
+	revision := r.getRevision()
+	err = provider.DownloadRepository(ctx, r.RepositoryOwner, r.RepositoryName, revision, buildDir)
+	if err != nil {
+		return r.wrapBuildError(ctx, err)
+	}
+
+	r.logger.Info("Starting to build image", "appName", r.AppName, "revision", revision)
 	err = r.customDockerClient.BuildImage(
+		ctx,
 		r.getImage(),
-		buildDir,
+		path.Join(buildDir, r.BuildContext),
+		path.Join(buildDir, r.DockefileLocation),
 	)
 
+	return r.wrapBuildError(ctx, err)
+}
+
+func (r repositoryBuildApp) wrapBuildError(ctx context.Context, err error) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("Build of app `%s` timed out after %s", r.AppName, r.BuildTimeout)
+	}
 	return err
 }
 
 func (r repositoryBuildApp) Configuration() AppConfiguration {
 	return AppConfiguration{
-		AppName: r.AppName,
-		Image:   r.getImage(),
+		AppName:     r.AppName,
+		Image:       r.getImage(),
+		Routes:      r.Routes,
+		Volumes:     r.Volumes,
+		Env:         r.Env,
+		SecretEnv:   r.SecretEnv,
+		SecretFiles: r.SecretFiles,
+		DependsOn:   r.DependsOn,
+		Healthcheck: r.Healthcheck,
+		Replicas:    r.Replicas,
 	}
 }
 
+func (r repositoryBuildApp) Definition() any {
+	return r.RepositoryBuildAppCreateOpts
+}
+
 func (r repositoryBuildApp) getImage() string {
-	return fmt.Sprintf("%s%s:%s", r.resourcePrefix, r.AppName, r.RepositoryRevision)
+	return fmt.Sprintf("%s%s:%s", r.resourcePrefix, r.AppName, r.getRevision())
+}
+
+// Resolved commit sha, or the configured revision when the app isn't resolved
+func (r repositoryBuildApp) getRevision() string {
+	if r.resolvedSha != "" {
+		return r.resolvedSha
+	}
+	return r.RepositoryRevision
 }
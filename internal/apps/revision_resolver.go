@@ -0,0 +1,93 @@
+package apps
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/krystofrezac/lifebuoy/internal/sources"
+)
+
+// Resolved revisions are reused for this long, so reconciles don't use up the rate limit of the provider
+const revisionCacheTtl = 60 * time.Second
+
+var commitShaRegex = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// Full commit sha can't move, so it doesn't have to be resolved
+func isCommitSha(revision string) bool {
+	return commitShaRegex.MatchString(revision)
+}
+
+type resolvedRevision struct {
+	sha        string
+	resolvedAt time.Time
+}
+
+// Resolves branches and tags to commit shas. Safe for concurrent use
+type revisionResolver struct {
+	logger *slog.Logger
+	mutex  sync.Mutex
+	// Key is `<provider key> <owner>/<repository>@<revision> <token id>`, the repository lower cased
+	cache map[string]resolvedRevision
+}
+
+func newRevisionResolver(logger *slog.Logger) *revisionResolver {
+	return &revisionResolver{logger: logger, cache: map[string]resolvedRevision{}}
+}
+
+func (r *revisionResolver) resolve(ctx context.Context, providerOpts sources.ProviderOpts, owner string, repository string, revision string) (string, error) {
+	if isCommitSha(revision) {
+		return revision, nil
+	}
+
+	provider, err := sources.NewProvider(r.logger, providerOpts)
+	if err != nil {
+		return "", err
+	}
+
+	// Longer when the rate limit is almost exhausted
+	ttl := max(revisionCacheTtl, provider.GetPollInterval())
+	key := getRevisionCacheKey(providerOpts, owner, repository, revision)
+	r.mutex.Lock()
+	cached, ok := r.cache[key]
+	r.mutex.Unlock()
+	if ok && time.Since(cached.resolvedAt) < ttl {
+		return cached.sha, nil
+	}
+
+	sha, err := provider.GetSha(ctx, owner, repository, &revision)
+	if err != nil {
+		return "", err
+	}
+
+	r.mutex.Lock()
+	r.cache[key] = resolvedRevision{sha: sha, resolvedAt: time.Now()}
+	r.mutex.Unlock()
+
+	return sha, nil
+}
+
+// Forgets resolved revisions of the repository, e.g. after a push
+func (r *revisionResolver) invalidate(providerOpts sources.ProviderOpts, owner string, repository string) {
+	prefix := getRevisionCacheKey(providerOpts, owner, repository, "")
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for key := range r.cache {
+		if strings.HasPrefix(key, prefix) {
+			delete(r.cache, key)
+		}
+	}
+}
+
+// Token goes last, so a push invalidates the revision for all tokens. Apps with different tokens can see different refs
+func getRevisionCacheKey(providerOpts sources.ProviderOpts, owner string, repository string, revision string) string {
+	key := providerOpts.Key() + " " + strings.ToLower(owner+"/"+repository) + "@"
+	if revision == "" {
+		return key
+	}
+	return key + revision + " " + providerOpts.TokenId()
+}
@@ -0,0 +1,62 @@
+package apps
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/krystofrezac/lifebuoy/internal/secrets"
+	"github.com/krystofrezac/lifebuoy/internal/sources"
+)
+
+func TestRevisionResolver_CommitShaIsNotResolved(t *testing.T) {
+	resolver := newRevisionResolver(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	sha := "0123456789abcdef0123456789abcdef01234567"
+
+	res, err := resolver.resolve(context.Background(), sources.ProviderOpts{Type: sources.ProviderGithub}, "owner", "repository", sha)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != sha {
+		t.Fatalf("Expected '%s', got '%s'", sha, res)
+	}
+}
+
+func TestRevisionResolver_UsesCacheUntilInvalidated(t *testing.T) {
+	resolver := newRevisionResolver(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	github := sources.ProviderOpts{Type: sources.ProviderGithub}
+	gitea := sources.ProviderOpts{Type: sources.ProviderGitea, BaseUrl: "https://gitea.example.com"}
+	resolver.cache[getRevisionCacheKey(github, "Owner", "repository", "main")] = resolvedRevision{sha: "cached", resolvedAt: time.Now()}
+	resolver.cache[getRevisionCacheKey(gitea, "owner", "repository", "main")] = resolvedRevision{sha: "other", resolvedAt: time.Now()}
+
+	res, err := resolver.resolve(context.Background(), github, "owner", "Repository", "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != "cached" {
+		t.Fatalf("Expected cached sha, got '%s'", res)
+	}
+
+	resolver.invalidate(github, "owner", "repository")
+	if len(resolver.cache) != 1 {
+		t.Fatalf("Expected only revision from the other provider, got %+v", resolver.cache)
+	}
+}
+
+func TestRevisionResolver_CacheIsPerToken(t *testing.T) {
+	resolver := newRevisionResolver(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	private := sources.ProviderOpts{Type: sources.ProviderGithub, Token: sources.NewStaticToken(secrets.NewValue("private-token"))}
+	anonymous := sources.ProviderOpts{Type: sources.ProviderGithub}
+	resolver.cache[getRevisionCacheKey(private, "owner", "repository", "secret-branch")] = resolvedRevision{sha: "private", resolvedAt: time.Now()}
+
+	if _, ok := resolver.cache[getRevisionCacheKey(anonymous, "owner", "repository", "secret-branch")]; ok {
+		t.Fatal("Expected revision resolved with a token not to be cached for apps without it")
+	}
+
+	resolver.invalidate(anonymous, "owner", "repository")
+	if len(resolver.cache) != 0 {
+		t.Fatalf("Expected push to invalidate revisions of all tokens, got %+v", resolver.cache)
+	}
+}
@@ -0,0 +1,391 @@
+package configuration
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/krystofrezac/lifebuoy/internal/apps"
+	"github.com/krystofrezac/lifebuoy/internal/secrets"
+	"github.com/krystofrezac/lifebuoy/internal/sources"
+	"gopkg.in/yaml.v3"
+)
+
+const defaultAppsConfigurationDir = "apps"
+
+type appConfiguration struct {
+	Version int `validate:"required,oneof=1"`
+	// nil = enabled. Containers of disabled apps are stopped, but kept together with their volumes
+	Enabled *bool
+	// Same as `enabled: false`
+	Paused bool
+	// Names of apps that have to be running (and healthy when they have a healthcheck) before this app is started.
+	// Existence and cycles are checked by [checkDependencies]
+	DependsOn []string `yaml:"dependsOn" validate:"unique"`
+	// Exactly one provider has to be set, checked by [checkSource]
+	Source struct {
+		Github *repositorySource
+		Gitlab *repositorySource
+		Gitea  *repositorySource
+	}
+	Build struct {
+		// Relative to the repository root
+		DockefileLocation string `yaml:"dockefileLocation" validate:"omitempty,relative_path"`
+		// Relative to the repository root
+		BuildContext string        `yaml:"buildContext" validate:"omitempty,relative_path"`
+		Timeout      time.Duration `validate:"omitempty,min=1s"`
+	}
+	Runtime struct {
+		Routes []struct {
+			Port uint16 `validate:"required"`
+			// <host>[/<path prefix>], e.g. example.com/api
+			Url string `validate:"required,route_url"`
+		} `validate:"dive"`
+		// Uniqueness of managed volume names and allowed host paths are checked by [checkVolumes]
+		Volumes []struct {
+			Type string `validate:"required,oneof=managed manual"`
+			// Only for managed volumes
+			Name string `validate:"required_if=Type managed,excluded_unless=Type managed,omitempty,volume_name"`
+			// Only for manual volumes. Path on the host
+			From string `validate:"required_if=Type manual,excluded_unless=Type manual,omitempty,absolute_path"`
+			// Path inside of the container
+			To       string `validate:"required,absolute_path"`
+			ReadOnly bool   `yaml:"readOnly"`
+		} `validate:"unique=To,dive"`
+		Env         map[string]string `validate:"dive,keys,env_name,endkeys"`
+		Healthcheck *struct {
+			// Run by the shell of the container, the app is healthy when it exits with 0
+			Command     string        `validate:"required"`
+			Interval    time.Duration `validate:"omitempty,min=1s"`
+			Timeout     time.Duration `validate:"omitempty,min=1s"`
+			Retries     int           `validate:"omitempty,min=1"`
+			StartPeriod time.Duration `yaml:"startPeriod" validate:"omitempty,min=0"`
+		}
+		// Containers of the app, routes are load balanced between them. Managed volumes are shared by all of them
+		Replicas int `validate:"omitempty,min=1"`
+		// Collisions with env are checked by [checkSecrets]
+		Secrets []struct {
+			// Encrypted with `lifebuoy secrets encrypt`
+			Value string `validate:"required,encrypted_secret"`
+			// Name of the environment variable
+			Env string `validate:"required_without=File,excluded_with=File,omitempty,env_name"`
+			// Path inside of the container
+			File string `validate:"required_without=Env,excluded_with=Env,omitempty,absolute_path"`
+		} `validate:"dive"`
+	}
+}
+
+// Repository the app is built from
+type repositorySource struct {
+	// Empty = the public instance of the provider. Required for Gitea
+	BaseUrl string `yaml:"baseUrl" validate:"omitempty,http_url"`
+	// Encrypted with `lifebuoy secrets encrypt`. Empty = token from the credentials of the server
+	Token string `validate:"omitempty,encrypted_secret"`
+	// Name of credentials of the server. Empty = the default token of the provider, if the server has one
+	Credentials string `validate:"excluded_with=Token"`
+	// User or organization. Group with subgroups for GitLab, e.g. `group/subgroup`
+	Owner      string `validate:"required"`
+	Repository string `validate:"required"`
+	Revision   string `validate:"required"`
+}
+
+const defaultDockefileLocation = "Dockerfile"
+const defaultBuildContext = "."
+const defaultBuildTimeout = 30 * time.Minute
+const defaultReplicas = 1
+
+// Paused apps can still be resumed by an override, see [ConfigurationManager.SetAppPaused]
+func (a appConfiguration) isPaused() bool {
+	return a.Paused || (a.Enabled != nil && !*a.Enabled)
+}
+
+type appConfigurationFile struct {
+	appName string
+	// Relative to the configuration root
+	filePath      string
+	configuration appConfiguration
+}
+
+// Reads and validates all app configuration files. Errors of all files are returned, not only the first one.
+// environment empty = no environment overlays
+func readAppConfigurationFiles(root string, appsDir string, environment string, allowedBindMountPaths []string) ([]appConfigurationFile, []ConfigurationError) {
+	validate := newValidator()
+
+	entries, err := os.ReadDir(path.Join(root, appsDir))
+	if err != nil {
+		return nil, []ConfigurationError{{File: appsDir, Message: err.Error()}}
+	}
+
+	bases, configurationErrors := readAppConfigurationBases(root, appsDir, entries)
+	overlays, overlaysErrors := readEnvironmentOverlays(root, environment)
+	configurationErrors = append(configurationErrors, overlaysErrors...)
+	if len(configurationErrors) > 0 {
+		return nil, configurationErrors
+	}
+
+	var files = make([]appConfigurationFile, 0, len(entries))
+	var appNames []string
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !isAppFile(entry.Name()) {
+			continue
+		}
+
+		filePath := path.Join(appsDir, entry.Name())
+		appNames = append(appNames, getConfigurationName(filePath))
+
+		file, fileErrors := readAppConfigurationFile(validate, root, filePath, bases, overlays, allowedBindMountPaths)
+		if len(fileErrors) > 0 {
+			configurationErrors = append(configurationErrors, fileErrors...)
+			continue
+		}
+		files = append(files, file)
+	}
+	configurationErrors = append(configurationErrors, checkOverlaysHaveApps(overlays, appNames)...)
+
+	dependenciesErrors := checkDependencies(files, appNames)
+	if len(dependenciesErrors) > 0 {
+		configurationErrors = append(configurationErrors, dependenciesErrors...)
+		files = slices.DeleteFunc(files, func(file appConfigurationFile) bool {
+			return slices.ContainsFunc(dependenciesErrors, func(configurationError ConfigurationError) bool {
+				return configurationError.AppName == file.appName
+			})
+		})
+	}
+
+	return files, configurationErrors
+}
+
+// filePath is relative to root. Validation runs on the configuration merged with its bases
+func readAppConfigurationFile(
+	validate *validator.Validate,
+	root string,
+	filePath string,
+	bases appConfigurationBases,
+	overlays environmentOverlays,
+	allowedBindMountPaths []string,
+) (appConfigurationFile, []ConfigurationError) {
+	appName := getConfigurationName(filePath)
+
+	effective, configurationErrors := readEffectiveAppConfiguration(root, filePath, bases, overlays)
+	if len(configurationErrors) > 0 {
+		return appConfigurationFile{}, configurationErrors
+	}
+
+	decoded := appConfiguration{}
+	err := decodeYamlDocument(effective, &decoded)
+	if err != nil {
+		configurationErrors = getDecodeErrors(err)
+		for i := range configurationErrors {
+			configurationErrors[i].File = filePath
+			configurationErrors[i].AppName = appName
+		}
+		return appConfigurationFile{}, configurationErrors
+	}
+	configurationErrors = append(configurationErrors, getValidationErrors(validate.Struct(decoded))...)
+	configurationErrors = append(configurationErrors, checkSource(decoded)...)
+	configurationErrors = append(configurationErrors, checkVolumes(decoded, allowedBindMountPaths)...)
+	configurationErrors = append(configurationErrors, checkSecrets(decoded)...)
+	if len(configurationErrors) > 0 {
+		for i := range configurationErrors {
+			configurationErrors[i].File = filePath
+			configurationErrors[i].AppName = appName
+		}
+		return appConfigurationFile{}, configurationErrors
+	}
+
+	return appConfigurationFile{
+		appName:       appName,
+		filePath:      filePath,
+		configuration: decoded,
+	}, nil
+}
+
+// Configuration has to be already validated
+func getRoutes(configuration appConfiguration) []apps.Route {
+	routes := make([]apps.Route, 0, len(configuration.Runtime.Routes))
+	for _, route := range configuration.Runtime.Routes {
+		host, pathPrefix, _ := parseRouteUrl(route.Url)
+		routes = append(routes, apps.Route{
+			Port:       route.Port,
+			Host:       host,
+			PathPrefix: pathPrefix,
+		})
+	}
+	return routes
+}
+
+// Unset values are left for Docker to default
+func getHealthcheck(configuration appConfiguration) *apps.Healthcheck {
+	healthcheck := configuration.Runtime.Healthcheck
+	if healthcheck == nil {
+		return nil
+	}
+
+	return &apps.Healthcheck{
+		Command:     healthcheck.Command,
+		Interval:    healthcheck.Interval,
+		Timeout:     healthcheck.Timeout,
+		Retries:     healthcheck.Retries,
+		StartPeriod: healthcheck.StartPeriod,
+	}
+}
+
+func getVolumes(configuration appConfiguration) []apps.Volume {
+	volumes := make([]apps.Volume, 0, len(configuration.Runtime.Volumes))
+	for _, volume := range configuration.Runtime.Volumes {
+		volumes = append(volumes, apps.Volume{
+			Type:     apps.VolumeType(volume.Type),
+			Name:     volume.Name,
+			From:     volume.From,
+			To:       volume.To,
+			ReadOnly: volume.ReadOnly,
+		})
+	}
+	return volumes
+}
+
+// Configuration has to be already validated
+func getRepositorySource(configuration appConfiguration) (sources.ProviderType, repositorySource) {
+	switch {
+	case configuration.Source.Gitlab != nil:
+		return sources.ProviderGitlab, *configuration.Source.Gitlab
+	case configuration.Source.Gitea != nil:
+		return sources.ProviderGitea, *configuration.Source.Gitea
+	}
+	return sources.ProviderGithub, *configuration.Source.Github
+}
+
+// Token of the app is decrypted and registered in the redactor, so it doesn't appear in logs.
+// Apps without a token use the credentials of the server
+func getRepositoryProvider(
+	file appConfigurationFile,
+	secretsKey *secrets.PrivateKey,
+	credentials sources.Credentials,
+	redactor *secrets.Redactor,
+) (sources.ProviderOpts, *ConfigurationError) {
+	providerType, source := getRepositorySource(file.configuration)
+	opts := sources.ProviderOpts{Type: providerType, BaseUrl: source.BaseUrl}
+	if source.Token == "" {
+		token, err := credentials.GetToken(providerType, source.BaseUrl, source.Credentials)
+		if err != nil {
+			return sources.ProviderOpts{}, &ConfigurationError{File: file.filePath, AppName: file.appName, Field: fmt.Sprintf("source.%s.credentials", providerType), Message: err.Error()}
+		}
+
+		opts.Token = token
+		return opts, nil
+	}
+
+	field := fmt.Sprintf("source.%s.token", providerType)
+	if secretsKey == nil {
+		return sources.ProviderOpts{}, &ConfigurationError{File: file.filePath, AppName: file.appName, Field: field, Message: "Token is encrypted, but no secrets key is configured"}
+	}
+
+	token, err := secretsKey.Decrypt(source.Token)
+	if err != nil {
+		return sources.ProviderOpts{}, &ConfigurationError{File: file.filePath, AppName: file.appName, Field: field, Message: err.Error()}
+	}
+	redactor.Register(token)
+
+	opts.Token = sources.NewStaticToken(token)
+	return opts, nil
+}
+
+// Decrypted values are registered in the redactor, so they don't appear in logs
+func decryptSecrets(file appConfigurationFile, secretsKey *secrets.PrivateKey, redactor *secrets.Redactor) (map[string]secrets.Value, map[string]secrets.Value, []ConfigurationError) {
+	secretEnv := map[string]secrets.Value{}
+	secretFiles := map[string]secrets.Value{}
+
+	var configurationErrors []ConfigurationError
+	for i, secret := range file.configuration.Runtime.Secrets {
+		value, err := secretsKey.Decrypt(secret.Value)
+		if err != nil {
+			configurationErrors = append(configurationErrors, ConfigurationError{
+				File:    file.filePath,
+				AppName: file.appName,
+				Field:   fmt.Sprintf("runtime.secrets[%d].value", i),
+				Message: err.Error(),
+			})
+			continue
+		}
+		redactor.Register(value)
+
+		if secret.Env != "" {
+			secretEnv[secret.Env] = value
+		} else {
+			secretFiles[secret.File] = value
+		}
+	}
+
+	if len(configurationErrors) > 0 {
+		return nil, nil, configurationErrors
+	}
+	return secretEnv, secretFiles, nil
+}
+
+// Returns the app configuration merged with its bases and the environment overlay
+func readEffectiveAppConfiguration(root string, filePath string, bases appConfigurationBases, overlays environmentOverlays) (map[string]any, []ConfigurationError) {
+	appName := getConfigurationName(filePath)
+
+	document, configurationErrors := readYamlDocument(root, filePath)
+	if len(configurationErrors) > 0 {
+		return nil, withAppName(configurationErrors, appName)
+	}
+
+	effective, err := bases.apply(document)
+	if err != nil {
+		return nil, []ConfigurationError{{File: filePath, AppName: appName, Field: extendsKey, Message: err.Error()}}
+	}
+
+	overlayFilePath, ok := overlays[appName]
+	if !ok {
+		return effective, nil
+	}
+
+	overlay, configurationErrors := readYamlDocument(root, overlayFilePath)
+	if len(configurationErrors) > 0 {
+		return nil, withAppName(configurationErrors, appName)
+	}
+	if _, ok := overlay[extendsKey]; ok {
+		return nil, []ConfigurationError{{File: overlayFilePath, AppName: appName, Field: extendsKey, Message: "Only apps can extend profiles"}}
+	}
+
+	return mergeYamlMaps(effective, overlay), nil
+}
+
+func withAppName(configurationErrors []ConfigurationError, appName string) []ConfigurationError {
+	for i := range configurationErrors {
+		configurationErrors[i].AppName = appName
+	}
+	return configurationErrors
+}
+
+// Fields that out doesn't have are rejected, so typos don't get silently ignored.
+// Lines of the encoded document don't match any file, so they are removed from the errors
+func decodeYamlDocument(document map[string]any, out any) error {
+	encoded, err := yaml.Marshal(document)
+	if err != nil {
+		return err
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(encoded))
+	decoder.KnownFields(true)
+	err = decoder.Decode(out)
+	if typeError, ok := err.(*yaml.TypeError); ok {
+		for i, message := range typeError.Errors {
+			typeError.Errors[i] = lineNumberRegex.ReplaceAllString(message, "")
+		}
+	}
+	return err
+}
+
+func withDefault[T comparable](value T, defaultValue T) T {
+	var zero T
+	if value == zero {
+		return defaultValue
+	}
+	return value
+}
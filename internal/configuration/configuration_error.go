@@ -0,0 +1,106 @@
+package configuration
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"gopkg.in/yaml.v3"
+)
+
+// Problem in the configuration repository, caused by its content
+type ConfigurationError struct {
+	// Relative to the configuration root
+	File string `json:"file"`
+	// App the error belongs to. Empty when the error affects the whole configuration
+	AppName string `json:"appName"`
+	// Path in the YAML document, e.g. `runtime.volumes[0].from`. Empty when the error isn't related to a single field
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e ConfigurationError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("%s: %s", e.File, e.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", e.File, e.Field, e.Message)
+}
+
+// Matches the messages of fields unknown to the decoded struct, e.g. `line 3: field foo not found in type configuration.appConfiguration`.
+// Line is optional, as it's removed from errors of merged documents
+var unknownFieldRegex = regexp.MustCompile(`^(line \d+: )?field (\S+) not found in type .+$`)
+
+// Prefix of decode errors, e.g. `line 3: `
+var lineNumberRegex = regexp.MustCompile(`^line \d+: `)
+
+// Every problem of the document is returned separately. File of the errors is left empty
+func getDecodeErrors(err error) []ConfigurationError {
+	typeError, ok := err.(*yaml.TypeError)
+	if !ok {
+		return []ConfigurationError{{Message: fmt.Sprintf("Failed to decode: %s", err.Error())}}
+	}
+
+	configurationErrors := make([]ConfigurationError, 0, len(typeError.Errors))
+	for _, message := range typeError.Errors {
+		if match := unknownFieldRegex.FindStringSubmatch(message); match != nil {
+			message = fmt.Sprintf("%sunknown field `%s`", match[1], match[2])
+		}
+		configurationErrors = append(configurationErrors, ConfigurationError{Message: fmt.Sprintf("Failed to decode: %s", message)})
+	}
+	return configurationErrors
+}
+
+// File of the errors is left empty
+func getValidationErrors(err error) []ConfigurationError {
+	if err == nil {
+		return nil
+	}
+
+	fieldErrors, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return []ConfigurationError{{Message: err.Error()}}
+	}
+
+	configurationErrors := make([]ConfigurationError, 0, len(fieldErrors))
+	for _, fieldError := range fieldErrors {
+		// Namespace starts with the name of the root struct
+		_, field, _ := strings.Cut(fieldError.Namespace(), ".")
+		configurationErrors = append(configurationErrors, ConfigurationError{
+			Field:   field,
+			Message: describeFieldError(fieldError),
+		})
+	}
+	return configurationErrors
+}
+
+func describeFieldError(fieldError validator.FieldError) string {
+	switch fieldError.Tag() {
+	case "required", "required_if", "required_without":
+		return "Is required"
+	case "excluded_unless", "excluded_with":
+		return "Must not be set"
+	case "oneof":
+		return fmt.Sprintf("Must be one of: %s", fieldError.Param())
+	case "min":
+		return fmt.Sprintf("Must be at least %s", fieldError.Param())
+	case "unique":
+		return fmt.Sprintf("Items must have unique `%s`", strings.ToLower(fieldError.Param()))
+	case "relative_path":
+		return "Must be a relative path that doesn't leave the repository"
+	case "absolute_path":
+		return "Must be an absolute path without `.` and `..` segments"
+	case "route_url":
+		return "Must be in format <host>[/<path prefix>]"
+	case "volume_name":
+		return "Can contain only letters, numbers, `.` and `-`"
+	case "env_name":
+		return "Can contain only letters, numbers and `_` and can't start with a number"
+	case "http_url":
+		return "Must be an HTTP(S) URL"
+	case "encrypted_secret":
+		return "Must be encrypted with `lifebuoy secrets encrypt`"
+	default:
+		return fmt.Sprintf("Failed on the `%s` validation", fieldError.Tag())
+	}
+}
@@ -4,246 +4,631 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
-	"os"
-	"path"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/go-playground/validator/v10"
 	"github.com/krystofrezac/lifebuoy/internal/apps"
 	containermanager "github.com/krystofrezac/lifebuoy/internal/container_manager"
-	"github.com/krystofrezac/lifebuoy/internal/github"
-	"gopkg.in/yaml.v3"
+	"github.com/krystofrezac/lifebuoy/internal/secrets"
+	"github.com/krystofrezac/lifebuoy/internal/sources"
 )
 
 type ConfigurationManager struct {
-	logger                    *slog.Logger
-	repositoryOwner           string
-	repositoryName            string
-	repositoryRevision        *string
-	githubToken               *string
-	managedStoragePath        string
+	logger             *slog.Logger
+	source             Source
+	managedStoragePath string
+	// Empty = no environment overlays
+	environment           string
+	allowedBindMountPaths []string
+	// nil = secrets can't be used
+	secretsKey *secrets.PrivateKey
+	// Tokens for app repositories
+	credentials               sources.Credentials
+	redactor                  *secrets.Redactor
 	resourcePrefix            string
 	repositoryBuildAppCreator apps.RepositoryBuildAppCreator
 	dockefileAppCreator       apps.DockerFileAppCreator
 	containerManager          containermanager.ContainerManager
 	ticker                    *time.Ticker
-	iterTimeout               time.Duration
-	downloadDir               string
-	appsConfigurationDir      string
+	// Used for values missing in the settings file
+	defaultSettings serverSettings
+	// Settings from the last applied revision
+	settings             serverSettings
+	githubPushChannel    chan GithubPush
+	planRequestChannel   chan planRequest
+	pauseRequestChannel  chan pauseRequest
+	appsConfigurationDir string
 	// nil = don't have apps yet
-	apps              []apps.App
+	apps            []apps.App
+	invalidAppNames []string
+	// Apps paused by the last applied configuration
+	configurationPausedAppNames []string
+	// Apps paused by the last applied configuration, created without their secrets and credentials
+	placeholderAppNames []string
+	pauseOverrides      pauseOverrides
+	// Apps paused by the configuration or by an override, as sent to the container manager
+	pausedAppNames    []string
 	lastRepositorySha string
-}
-
-type appConfiguration struct {
-	Version int `validate:"required,oneof=1"`
-	Source  struct {
-		Github struct {
-			Owner      string `validate:"required"`
-			Repository string `valiadte:"required"`
-			Revision   string `validate:"required"`
-		}
-	}
+	lastCheckAt       time.Time
+	appErrorsMutex    sync.Mutex
+	// Errors of apps that are invalid in the last checked revision, by app name
+	appErrors map[string][]ConfigurationError
 }
 
 func NewConfigurationManager(
 	logger *slog.Logger,
-	repositoryOwner string,
-	repositoryName string,
-	repositoryRevision *string,
-	githubToken *string,
+	source Source,
 	managedStoragePath string,
+	environment string,
+	allowedBindMountPaths []string,
+	secretsKey *secrets.PrivateKey,
+	credentials sources.Credentials,
+	redactor *secrets.Redactor,
 	resourcePrefix string,
 	repositoryBuildAppCreator apps.RepositoryBuildAppCreator,
 	dockefileAppCreator apps.DockerFileAppCreator,
 	containerManager containermanager.ContainerManager,
+	webhooksEnabled bool,
 ) *ConfigurationManager {
-	// TODO: make it configurable, beware the rate limit
-	const tickInterval = 60 * time.Second
-	const iterTimeout = 10 * time.Second
-	const downloadDir = "configuration"
-	const appsConfigurationDir = "apps"
-
-	ticker := time.NewTicker(tickInterval)
+	settings := getDefaultServerSettings(webhooksEnabled)
+	ticker := time.NewTicker(settings.PollInterval)
 
 	return &ConfigurationManager{
 		logger:                    logger,
-		repositoryOwner:           repositoryOwner,
-		repositoryName:            repositoryName,
-		repositoryRevision:        repositoryRevision,
-		githubToken:               githubToken,
+		source:                    source,
 		managedStoragePath:        managedStoragePath,
+		environment:               environment,
+		allowedBindMountPaths:     allowedBindMountPaths,
+		secretsKey:                secretsKey,
+		credentials:               credentials,
+		redactor:                  redactor,
 		resourcePrefix:            resourcePrefix,
 		repositoryBuildAppCreator: repositoryBuildAppCreator,
 		dockefileAppCreator:       dockefileAppCreator,
 		containerManager:          containerManager,
 		ticker:                    ticker,
-		iterTimeout:               iterTimeout,
-		downloadDir:               downloadDir,
-		appsConfigurationDir:      appsConfigurationDir,
-		apps:                      nil,
-		lastRepositorySha:         "",
+		defaultSettings:           settings,
+		settings:                  settings,
+		// Buffered, so webhook requests don't wait for a running check
+		githubPushChannel:    make(chan GithubPush, 16),
+		planRequestChannel:   make(chan planRequest),
+		pauseRequestChannel:  make(chan pauseRequest),
+		appsConfigurationDir: defaultAppsConfigurationDir,
+		pauseOverrides:       pauseOverrides{},
+		apps:                 nil,
+		lastRepositorySha:    "",
+		appErrors:            map[string][]ConfigurationError{},
 	}
 }
 
 func (c *ConfigurationManager) Start(ctx context.Context) {
+	overrides, err := readPauseOverrides(c.managedStoragePath)
+	if err != nil {
+		c.logger.Error("Failed to read pause overrides", "err", err)
+	} else {
+		c.pauseOverrides = overrides
+	}
+
+	c.restoreSnapshot()
 	c.checkForChanges(ctx)
-	for range c.ticker.C {
+	for {
+		select {
+		case <-c.ticker.C:
+			if c.isCheckPostponed() {
+				continue
+			}
+			c.checkForChanges(ctx)
+		case push := <-c.githubPushChannel:
+			c.handleGithubPush(ctx, push)
+		case request := <-c.planRequestChannel:
+			plan, err := c.plan(request.ctx, request.revision)
+			request.resultChannel <- planResult{plan: plan, err: err}
+		case request := <-c.pauseRequestChannel:
+			paused, err := c.setAppPaused(request.ctx, request.appName, request.paused)
+			request.resultChannel <- pauseResult{paused: paused, err: err}
+		}
+	}
+}
+
+type planRequest struct {
+	ctx           context.Context
+	revision      string
+	resultChannel chan<- planResult
+}
+
+type planResult struct {
+	plan RevisionPlan
+	err  error
+}
+
+// Loads the revision and compares it with the state of Docker without changing anything. revision empty = the latest revision.
+// Runs between configuration checks, because they share the checkout
+func (c *ConfigurationManager) Plan(ctx context.Context, revision string) (RevisionPlan, error) {
+	resultChannel := make(chan planResult, 1)
+	select {
+	case c.planRequestChannel <- planRequest{ctx: ctx, revision: revision, resultChannel: resultChannel}:
+	case <-ctx.Done():
+		return RevisionPlan{}, ctx.Err()
+	}
+
+	select {
+	case result := <-resultChannel:
+		return result.plan, result.err
+	case <-ctx.Done():
+		return RevisionPlan{}, ctx.Err()
+	}
+}
+
+func (c *ConfigurationManager) plan(ctx context.Context, revision string) (RevisionPlan, error) {
+	if revision == "" {
+		var err error
+		revision, err = c.source.GetRevision(ctx)
+		if err != nil {
+			return RevisionPlan{}, err
+		}
+	}
+
+	configPath, err := c.source.Checkout(ctx, revision)
+	if err != nil {
+		return RevisionPlan{}, err
+	}
+
+	loaded, err := c.loadConfiguration(configPath)
+	if err != nil {
+		return RevisionPlan{}, err
+	}
+
+	pausedAppNames := getPausedAppNames(loaded.pausedAppNames, c.pauseOverrides)
+	plan, err := c.containerManager.Plan(ctx, loaded.apps, loaded.getInvalidAppNames(), pausedAppNames)
+	if err != nil {
+		return RevisionPlan{}, err
+	}
+
+	return RevisionPlan{Revision: revision, Plan: plan, AppErrors: loaded.appErrors}, nil
+}
+
+// Push to a Github repository, reported by a webhook
+type GithubPush struct {
+	Owner      string
+	Repository string
+	// Full name of the pushed ref, e.g. `refs/heads/main`
+	Ref string
+}
+
+// Pushes are handled asynchronously. When too many of them are waiting, the push is dropped and polling picks up the change later
+func (c *ConfigurationManager) HandleGithubPush(push GithubPush) {
+	select {
+	case c.githubPushChannel <- push:
+	default:
+		c.logger.Warn("Too many pushes waiting, dropping push", "owner", push.Owner, "repository", push.Repository, "ref", push.Ref)
+	}
+}
+
+func (c *ConfigurationManager) handleGithubPush(ctx context.Context, push GithubPush) {
+	c.logger.Debug("Handling Github push", "owner", push.Owner, "repository", push.Repository, "ref", push.Ref)
+
+	source, ok := c.source.(githubRepositorySource)
+	if !ok || source.IsGithubRepository(push.Owner, push.Repository) {
 		c.checkForChanges(ctx)
 	}
+
+	revision := strings.TrimPrefix(strings.TrimPrefix(push.Ref, "refs/heads/"), "refs/tags/")
+	var appNames []string
+	for _, app := range c.apps {
+		definition, ok := app.Definition().(apps.RepositoryBuildAppCreateOpts)
+		if !ok || definition.RepositoryProvider.Type != sources.ProviderGithub {
+			continue
+		}
+
+		if strings.EqualFold(definition.RepositoryOwner, push.Owner) &&
+			strings.EqualFold(definition.RepositoryName, push.Repository) &&
+			definition.RepositoryRevision == revision {
+			appNames = append(appNames, definition.AppName)
+			c.repositoryBuildAppCreator.InvalidateRevisions(definition.RepositoryProvider, definition.RepositoryOwner, definition.RepositoryName)
+		}
+	}
+
+	if len(appNames) > 0 {
+		c.logger.Info("Source of apps was pushed, reconciling them", "appNames", appNames)
+		c.containerManager.ReconcileApps(appNames)
+	}
+}
+
+// The source asks for a longer poll interval, e.g. because its rate limit is almost exhausted
+func (c *ConfigurationManager) isCheckPostponed() bool {
+	source, ok := c.source.(rateLimitedSource)
+	if !ok {
+		return false
+	}
+
+	pollInterval := source.GetPollInterval()
+	if time.Since(c.lastCheckAt) >= pollInterval {
+		return false
+	}
+	c.logger.Debug("Configuration check postponed because of rate limit", "pollInterval", pollInterval)
+	return true
 }
 
 func (c *ConfigurationManager) checkForChanges(ctx context.Context) {
 	c.logger.Debug("Configuration check started")
-	ctx, cancel := context.WithTimeout(ctx, c.iterTimeout)
+	c.lastCheckAt = time.Now()
+	ctx, cancel := context.WithTimeout(ctx, c.settings.CheckTimeout)
 	defer cancel()
 
-	configPath := path.Join(c.managedStoragePath, c.downloadDir)
-
-	revisionSha, err := github.GetSha(ctx, c.repositoryOwner, c.repositoryName, c.repositoryRevision, c.githubToken)
+	revisionSha, err := c.source.GetRevision(ctx)
 	if err != nil {
-		c.logger.Error("Failed to get revision sha", "err", err)
+		c.logger.Error("Failed to get configuration revision", "err", err)
 		return
 	}
 
 	if c.lastRepositorySha == revisionSha {
-		c.logger.Debug("Configuration sha haven't changed")
+		c.logger.Debug("Configuration revision haven't changed")
 		return
 	}
-	c.lastRepositorySha = revisionSha
 
-	err = github.DownloadRepository(
-		ctx,
-		c.repositoryOwner,
-		c.repositoryName,
-		c.repositoryRevision,
-		c.githubToken,
-		configPath,
-	)
+	configPath, err := c.source.Checkout(ctx, revisionSha)
 	if err != nil {
-		c.logger.Error("Failed to download config repository", "err", err)
+		c.logger.Error("Failed to checkout configuration", "err", err)
 		return
 	}
 
-	apps, err := c.readAppConfigurations(path.Join(configPath, c.appsConfigurationDir))
+	loaded, err := c.loadConfiguration(configPath)
 	if err != nil {
-		c.logger.Error("Failed to read app configurations", "err", err)
+		// The revision isn't remembered, so it's tried again on the next check
+		c.logger.Error("Failed to load configuration", "err", err)
 		return
 	}
-	apps = append(apps, c.getDefaultApps()...)
 
-	didAppsChange := c.didAppsChange(apps)
-	c.apps = apps
-
-	err = c.checkAppsNameCollisions()
+	err = c.applyConfiguration(revisionSha, loaded)
 	if err != nil {
-		c.logger.Error(err.Error())
+		// Rejected configuration mustn't replace the snapshot of the last applied one
+		c.logger.Error("Failed to apply configuration", "revision", revisionSha, "err", err)
 		return
 	}
 
-	if didAppsChange {
-		c.logger.Info("Apps configuration changed")
-		c.containerManager.UpdateApps(apps)
+	err = saveSnapshot(c.managedStoragePath, configPath, revisionSha)
+	if err != nil {
+		c.logger.Error("Failed to save snapshot of the configuration", "err", err)
 	}
 
 	c.logger.Debug("Configuration check finished")
 }
 
-func (c *ConfigurationManager) readAppConfigurations(dir string) ([]apps.App, error) {
-	validate := validator.New(validator.WithRequiredStructEnabled())
+// Rejected configuration doesn't change anything, so the last applied one keeps running and the revision is checked again
+func (c *ConfigurationManager) applyConfiguration(revision string, loaded loadedConfiguration) error {
+	err := checkAppsNameCollisions(loaded.apps)
+	if err != nil {
+		return err
+	}
+
+	c.applyServerSettings(loaded.settings)
+	c.setAppErrors(loaded.appErrors)
+	c.lastRepositorySha = revision
+	c.applyApps(revision, loaded)
+	return nil
+}
+
+func (c *ConfigurationManager) applyApps(revision string, loaded loadedConfiguration) {
+	changeset := apps.Diff(c.apps, loaded.apps)
+	isFirstConfiguration := c.apps == nil
+	c.apps = loaded.apps
+	c.invalidAppNames = loaded.getInvalidAppNames()
+	c.configurationPausedAppNames = loaded.pausedAppNames
+	c.placeholderAppNames = loaded.placeholderAppNames
+
+	if isFirstConfiguration || !changeset.IsEmpty() {
+		c.logger.Info("Apps configuration changed", "revision", revision, "changes", changeset.String())
+		c.pausedAppNames = getPausedAppNames(c.configurationPausedAppNames, c.pauseOverrides)
+		c.containerManager.UpdateApps(c.apps, changeset, c.invalidAppNames, c.pausedAppNames)
+		return
+	}
+	c.applyPausedApps()
+}
 
-	entries, err := os.ReadDir(dir)
+// Applies the last applied configuration, so apps are reconciled even when the configuration source is unreachable
+func (c *ConfigurationManager) restoreSnapshot() {
+	configPath, revision, err := loadSnapshot(c.managedStoragePath)
 	if err != nil {
-		return nil, err
+		c.logger.Error("Failed to load snapshot of the configuration", "err", err)
+		return
+	}
+	if revision == "" {
+		c.logger.Debug("There is no snapshot of the configuration")
+		return
 	}
 
-	var appConfigurations = make([]apps.App, 0, len(entries))
-	for _, entry := range entries {
-		if !entry.Type().IsRegular() {
-			continue
-		}
+	loaded, err := c.loadConfiguration(configPath)
+	if err != nil {
+		c.logger.Error("Failed to load snapshot of the configuration", "err", err)
+		return
+	}
 
-		fileName := entry.Name()
-		filePath := path.Join(dir, fileName)
-		file, err := os.Open(filePath)
-		if err != nil {
-			return nil, err
-		}
+	c.logger.Info("Restoring snapshot of the configuration", "revision", revision)
+	err = c.applyConfiguration(revision, loaded)
+	if err != nil {
+		c.logger.Error("Failed to apply snapshot of the configuration", "revision", revision, "err", err)
+	}
+}
 
-		appName := strings.Split(fileName, ".")[0]
-		decoded := appConfiguration{}
+type loadedConfiguration struct {
+	settings serverSettings
+	// Including the default apps. Invalid apps have their last known-good definition
+	apps []apps.App
+	// Apps paused by the configuration. Invalid apps keep their last known state
+	pausedAppNames []string
+	// Valid apps created without their secrets and credentials, because they are paused by the configuration
+	placeholderAppNames []string
+	// By app name
+	appErrors map[string][]ConfigurationError
+}
 
-		err = yaml.NewDecoder(file).Decode(&decoded)
-		if err != nil {
-			return nil, fmt.Errorf("Failed to decode configuration file `%s`. Error: %s", filePath, err.Error())
+func (l loadedConfiguration) getInvalidAppNames() []string {
+	res := make([]string, 0, len(l.appErrors))
+	for appName := range l.appErrors {
+		res = append(res, appName)
+	}
+	slices.Sort(res)
+	return res
+}
+
+// Configuration errors are logged. Returns error when the configuration as a whole can't be used
+func (c *ConfigurationManager) loadConfiguration(configPath string) (loadedConfiguration, error) {
+	settings, configurationErrors := readServerSettings(configPath, c.defaultSettings)
+	if len(configurationErrors) > 0 {
+		for _, configurationError := range configurationErrors {
+			c.logger.Error("Invalid server settings", "file", configurationError.File, "field", configurationError.Field, "err", configurationError.Message)
 		}
+		return loadedConfiguration{}, fmt.Errorf("Failed to read server settings")
+	}
 
-		err = validate.Struct(decoded)
-		if err != nil {
-			return nil, err
+	newApps, pausedAppNames, configurationErrors := c.readAppConfigurations(configPath)
+	placeholderAppNames := slices.DeleteFunc(slices.Clone(pausedAppNames), c.isResumedByOverride)
+	for _, configurationError := range configurationErrors {
+		c.logger.Error("Invalid app configuration", "appName", configurationError.AppName, "file", configurationError.File, "field", configurationError.Field, "err", configurationError.Message)
+	}
+
+	newApps, appErrors, err := c.withKnownGoodApps(newApps, configurationErrors)
+	if err != nil {
+		return loadedConfiguration{}, fmt.Errorf("Failed to read app configurations: %w", err)
+	}
+	for appName := range appErrors {
+		if slices.Contains(c.configurationPausedAppNames, appName) {
+			pausedAppNames = append(pausedAppNames, appName)
 		}
+	}
+	slices.Sort(pausedAppNames)
+
+	return loadedConfiguration{
+		settings:            settings,
+		apps:                append(newApps, c.getDefaultApps(settings)...),
+		pausedAppNames:      pausedAppNames,
+		placeholderAppNames: placeholderAppNames,
+		appErrors:           appErrors,
+	}, nil
+}
 
-		app := c.repositoryBuildAppCreator.Create(apps.RepositoryBuildAppCreateOpts{
-			AppName:            appName,
-			RepositoryOwner:    decoded.Source.Github.Owner,
-			RepositoryName:     decoded.Source.Github.Repository,
-			RepositoryRevision: decoded.Source.Github.Revision,
-		})
+func (c *ConfigurationManager) applyServerSettings(settings serverSettings) {
+	if c.settings == settings {
+		return
+	}
+	c.logger.Info("Server settings changed", "settings", settings)
 
-		appConfigurations = append(appConfigurations, app)
+	if c.settings.PollInterval != settings.PollInterval {
+		c.ticker.Reset(settings.PollInterval)
+	}
+	if c.settings.ReconcileInterval != settings.ReconcileInterval || c.settings.BuildConcurrency != settings.BuildConcurrency {
+		c.containerManager.UpdateSettings(containermanager.Settings{
+			ReconcileInterval: settings.ReconcileInterval,
+			BuildConcurrency:  settings.BuildConcurrency,
+		})
 	}
 
-	return appConfigurations, nil
+	c.settings = settings
 }
 
-func (c *ConfigurationManager) getDefaultApps() []apps.App {
-	return []apps.App{
-		c.dockefileAppCreator.Create(apps.DockefileAppCreateOpts{
-			AppName: "internal.traefik",
-			Dockerfile: `
-				FROM traefik:v3.1.0
-				RUN mkdir /etc/traefik
-				RUN echo "providers: {'docker': {}}" > /etc/traefik/traefik.yml
-				`,
-		}),
+// Returns errors of apps that are invalid in the last checked revision, by app name
+func (c *ConfigurationManager) AppErrors() map[string][]ConfigurationError {
+	c.appErrorsMutex.Lock()
+	defer c.appErrorsMutex.Unlock()
+
+	res := make(map[string][]ConfigurationError, len(c.appErrors))
+	for appName, appErrors := range c.appErrors {
+		res[appName] = slices.Clone(appErrors)
 	}
+	return res
 }
 
-func (c *ConfigurationManager) didAppsChange(newApps []apps.App) bool {
-	if c.apps == nil {
-		return true
+func (c *ConfigurationManager) setAppErrors(appErrors map[string][]ConfigurationError) {
+	c.appErrorsMutex.Lock()
+	defer c.appErrorsMutex.Unlock()
+
+	c.appErrors = appErrors
+}
+
+// Invalid apps are replaced with their last known-good definition, so an error in one app file doesn't block changes of other apps.
+// Apps are sorted by name. Returns error when the configuration as a whole is invalid
+func (c *ConfigurationManager) withKnownGoodApps(validApps []apps.App, configurationErrors []ConfigurationError) ([]apps.App, map[string][]ConfigurationError, error) {
+	appErrors := make(map[string][]ConfigurationError)
+	for _, configurationError := range configurationErrors {
+		if configurationError.AppName == "" {
+			return nil, nil, configurationError
+		}
+		appErrors[configurationError.AppName] = append(appErrors[configurationError.AppName], configurationError)
 	}
 
-	if len(c.apps) != len(newApps) {
-		return true
+	// Multiple files with the same app name would fight over the same containers
+	validAppsByName := make(map[string][]apps.App, len(validApps))
+	for _, app := range validApps {
+		appName := app.Configuration().AppName
+		validAppsByName[appName] = append(validAppsByName[appName], app)
+	}
+	for appName, sameNameApps := range validAppsByName {
+		if len(sameNameApps) > 1 {
+			appErrors[appName] = append(appErrors[appName], ConfigurationError{
+				File:    c.appsConfigurationDir,
+				AppName: appName,
+				Message: "There are multiple apps with the same name",
+			})
+		}
 	}
 
-	for i, lastItem := range c.apps {
-		newItem := newApps[i]
-		if lastItem != newItem {
-			return true
+	res := make([]apps.App, 0, len(validApps))
+	for _, app := range validApps {
+		if _, invalid := appErrors[app.Configuration().AppName]; !invalid {
+			res = append(res, app)
 		}
 	}
 
-	return false
+	for _, lastApp := range c.apps {
+		appName := lastApp.Configuration().AppName
+		if _, invalid := appErrors[appName]; !invalid {
+			continue
+		}
+		// It would run without its secrets. Without a definition the app isn't started, but its containers are kept
+		if slices.Contains(c.placeholderAppNames, appName) {
+			c.logger.Warn("Last definition of invalid app is incomplete, the app won't be started", "appName", appName)
+			continue
+		}
+
+		c.logger.Warn("Keeping last known-good definition of invalid app", "appName", appName)
+		res = append(res, lastApp)
+	}
+
+	slices.SortFunc(res, func(a apps.App, b apps.App) int {
+		return strings.Compare(a.Configuration().AppName, b.Configuration().AppName)
+	})
+
+	return res, appErrors, nil
 }
 
-func (c *ConfigurationManager) checkAppsNameCollisions() error {
-	grouped := make(map[string]int, len(c.apps))
-	for _, app := range c.apps {
-		name := app.Configuration().AppName
-		prev, prevOk := grouped[name]
-		if prevOk {
-			grouped[name] = prev + 1
-		} else {
-			grouped[name] = 0
+// root is the root of the configuration. Returns the apps and names of the paused ones
+func (c *ConfigurationManager) readAppConfigurations(root string) ([]apps.App, []string, []ConfigurationError) {
+	files, configurationErrors := readAppConfigurationFiles(root, c.appsConfigurationDir, c.environment, c.allowedBindMountPaths)
+
+	var appConfigurations = make([]apps.App, 0, len(files))
+	var pausedAppNames []string
+	for _, file := range files {
+		decoded := file.configuration
+		if decoded.isPaused() {
+			pausedAppNames = append(pausedAppNames, file.appName)
+		}
+
+		// Secrets and credentials are resolved once the app is resumed, so a host without them can keep the app paused, e.g. by an environment overlay
+		if decoded.isPaused() && !c.isResumedByOverride(file.appName) {
+			providerType, source := getRepositorySource(decoded)
+			appConfigurations = append(appConfigurations, c.createRepositoryBuildApp(
+				file,
+				sources.ProviderOpts{Type: providerType, BaseUrl: source.BaseUrl},
+				nil,
+				nil,
+			))
+			continue
 		}
+
+		if len(decoded.Runtime.Secrets) > 0 && c.secretsKey == nil {
+			configurationErrors = append(configurationErrors, ConfigurationError{
+				File:    file.filePath,
+				AppName: file.appName,
+				Field:   "runtime.secrets",
+				Message: "App uses secrets, but no secrets key is configured",
+			})
+			continue
+		}
+
+		secretEnv, secretFiles, secretsErrors := decryptSecrets(file, c.secretsKey, c.redactor)
+		if len(secretsErrors) > 0 {
+			configurationErrors = append(configurationErrors, secretsErrors...)
+			continue
+		}
+
+		providerOpts, providerError := getRepositoryProvider(file, c.secretsKey, c.credentials, c.redactor)
+		if providerError != nil {
+			configurationErrors = append(configurationErrors, *providerError)
+			continue
+		}
+
+		appConfigurations = append(appConfigurations, c.createRepositoryBuildApp(file, providerOpts, secretEnv, secretFiles))
+	}
+
+	return appConfigurations, pausedAppNames, configurationErrors
+}
+
+func (c *ConfigurationManager) isResumedByOverride(appName string) bool {
+	paused, ok := c.pauseOverrides[appName]
+	return ok && !paused
+}
+
+func (c *ConfigurationManager) createRepositoryBuildApp(
+	file appConfigurationFile,
+	providerOpts sources.ProviderOpts,
+	secretEnv map[string]secrets.Value,
+	secretFiles map[string]secrets.Value,
+) apps.App {
+	decoded := file.configuration
+	_, source := getRepositorySource(decoded)
+	return c.repositoryBuildAppCreator.Create(apps.RepositoryBuildAppCreateOpts{
+		AppName:            file.appName,
+		RepositoryProvider: providerOpts,
+		RepositoryOwner:    source.Owner,
+		RepositoryName:     source.Repository,
+		RepositoryRevision: source.Revision,
+		DockefileLocation:  withDefault(decoded.Build.DockefileLocation, defaultDockefileLocation),
+		BuildContext:       withDefault(decoded.Build.BuildContext, defaultBuildContext),
+		BuildTimeout:       withDefault(decoded.Build.Timeout, defaultBuildTimeout),
+		Routes:             getRoutes(decoded),
+		Volumes:            getVolumes(decoded),
+		Env:                decoded.Runtime.Env,
+		DependsOn:          decoded.DependsOn,
+		Healthcheck:        getHealthcheck(decoded),
+		Replicas:           withDefault(decoded.Runtime.Replicas, defaultReplicas),
+		SecretEnv:          secretEnv,
+		SecretFiles:        secretFiles,
+	})
+}
+
+func (c *ConfigurationManager) getDefaultApps(settings serverSettings) []apps.App {
+	traefikDockerfile := settings.Traefik.Dockerfile
+	if traefikDockerfile == "" {
+		traefikDockerfile = fmt.Sprintf(`
+				FROM %s
+				RUN mkdir /etc/traefik
+				RUN echo "{providers: {docker: {exposedByDefault: false}}, entryPoints: {%s: {address: ':80'}}}" > /etc/traefik/traefik.yml
+				`,
+			settings.Traefik.Image,
+			apps.TraefikEntrypoint,
+		)
+	}
+
+	return []apps.App{
+		c.dockefileAppCreator.Create(apps.DockefileAppCreateOpts{
+			AppName:      "internal.traefik",
+			Dockerfile:   traefikDockerfile,
+			Binds:        []string{"/var/run/docker.sock:/var/run/docker.sock:ro"},
+			PortMappings: []string{"80:80"},
+		}),
+	}
+}
+
+func checkAppsNameCollisions(appsToCheck []apps.App) error {
+	names := make([]string, 0, len(appsToCheck))
+	for _, app := range appsToCheck {
+		names = append(names, app.Configuration().AppName)
+	}
+
+	return checkNameCollisions(names)
+}
+
+func checkNameCollisions(names []string) error {
+	grouped := make(map[string]int, len(names))
+	for _, name := range names {
+		grouped[name]++
 	}
 
 	var collisions []string
 	for name, count := range grouped {
-		if count > 0 {
+		if count > 1 {
 			collisions = append(collisions, name)
 		}
 	}
@@ -252,5 +637,6 @@ func (c *ConfigurationManager) checkAppsNameCollisions() error {
 		return nil
 	}
 
+	slices.Sort(collisions)
 	return fmt.Errorf("There are multiple apps with the same name. Duplicate names %+v", collisions)
 }
@@ -1,7 +1,11 @@
 package configuration
 
 import (
+	"io"
+	"log/slog"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/docker/docker/client"
 	"github.com/krystofrezac/lifebuoy/internal/apps"
@@ -9,26 +13,26 @@ import (
 
 func TestCheckAppsNameCollisions_UniqueNames(t *testing.T) {
 	appCreator := apps.NewDockefileAppCreator(nil, &client.Client{})
-	c := ConfigurationManager{apps: []apps.App{
+	appsToCheck := []apps.App{
 		appCreator.Create(apps.DockefileAppCreateOpts{AppName: "app-1"}),
 		appCreator.Create(apps.DockefileAppCreateOpts{AppName: "app-2"}),
 		appCreator.Create(apps.DockefileAppCreateOpts{AppName: "app-3"}),
-	}}
+	}
 
-	if c.checkAppsNameCollisions() != nil {
+	if checkAppsNameCollisions(appsToCheck) != nil {
 		t.Fatal("Expected nil")
 	}
 }
 
 func TestCheckAppsNameCollisions_SameNames(t *testing.T) {
 	appCreator := apps.NewDockefileAppCreator(nil, &client.Client{})
-	c := ConfigurationManager{apps: []apps.App{
+	appsToCheck := []apps.App{
 		appCreator.Create(apps.DockefileAppCreateOpts{AppName: "app-1"}),
 		appCreator.Create(apps.DockefileAppCreateOpts{AppName: "app-2"}),
 		appCreator.Create(apps.DockefileAppCreateOpts{AppName: "app-1"}),
-	}}
+	}
 
-	res := c.checkAppsNameCollisions()
+	res := checkAppsNameCollisions(appsToCheck)
 	if res == nil {
 		t.Fatal("Expected error")
 	}
@@ -38,3 +42,87 @@ func TestCheckAppsNameCollisions_SameNames(t *testing.T) {
 		t.Fatalf("Expected error '%s'", exptedErrorMsg)
 	}
 }
+
+func TestWithKnownGoodApps_KeepsLastDefinitionOfInvalidApp(t *testing.T) {
+	appCreator := apps.NewDockefileAppCreator(nil, &client.Client{})
+	c := ConfigurationManager{
+		logger:               slog.New(slog.NewTextHandler(io.Discard, nil)),
+		appsConfigurationDir: defaultAppsConfigurationDir,
+		apps: []apps.App{
+			appCreator.Create(apps.DockefileAppCreateOpts{AppName: "app-1", Dockerfile: "FROM old"}),
+			appCreator.Create(apps.DockefileAppCreateOpts{AppName: "app-2", Dockerfile: "FROM old"}),
+		},
+	}
+
+	res, appErrors, err := c.withKnownGoodApps(
+		[]apps.App{
+			appCreator.Create(apps.DockefileAppCreateOpts{AppName: "app-1", Dockerfile: "FROM new"}),
+			appCreator.Create(apps.DockefileAppCreateOpts{AppName: "app-3", Dockerfile: "FROM new"}),
+			appCreator.Create(apps.DockefileAppCreateOpts{AppName: "app-3", Dockerfile: "FROM new"}),
+		},
+		[]ConfigurationError{{File: "apps/app-2.yaml", AppName: "app-2", Message: "Failed to decode"}},
+	)
+	if err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+
+	expected := []apps.App{
+		appCreator.Create(apps.DockefileAppCreateOpts{AppName: "app-1", Dockerfile: "FROM new"}),
+		appCreator.Create(apps.DockefileAppCreateOpts{AppName: "app-2", Dockerfile: "FROM old"}),
+	}
+	if !reflect.DeepEqual(res, expected) {
+		t.Fatalf("Expected %+v, got %+v", expected, res)
+	}
+
+	if len(appErrors) != 2 || len(appErrors["app-2"]) != 1 || len(appErrors["app-3"]) != 1 {
+		t.Fatalf("Expected errors of app-2 and app-3, got %+v", appErrors)
+	}
+}
+
+func TestWithKnownGoodApps_FailsOnConfigurationError(t *testing.T) {
+	c := ConfigurationManager{}
+
+	_, _, err := c.withKnownGoodApps(nil, []ConfigurationError{{File: "apps", Message: "no such file or directory"}})
+	if err == nil {
+		t.Fatal("Expected error")
+	}
+}
+
+func TestApplyConfiguration_CollidingRevisionChangesNothing(t *testing.T) {
+	appCreator := apps.NewDockefileAppCreator(nil, &client.Client{})
+	settings := getDefaultServerSettings(false)
+	lastApps := []apps.App{appCreator.Create(apps.DockefileAppCreateOpts{AppName: "app-1", Dockerfile: "FROM old"})}
+	c := ConfigurationManager{
+		logger:            slog.New(slog.NewTextHandler(io.Discard, nil)),
+		ticker:            time.NewTicker(settings.PollInterval),
+		settings:          settings,
+		apps:              lastApps,
+		lastRepositorySha: "revision-1",
+	}
+
+	changedSettings := settings
+	changedSettings.PollInterval = 2 * settings.PollInterval
+	err := c.applyConfiguration("revision-2", loadedConfiguration{
+		settings: changedSettings,
+		apps: []apps.App{
+			appCreator.Create(apps.DockefileAppCreateOpts{AppName: "app-1", Dockerfile: "FROM new"}),
+			appCreator.Create(apps.DockefileAppCreateOpts{AppName: "app-1", Dockerfile: "FROM new"}),
+		},
+		appErrors: map[string][]ConfigurationError{"app-2": {{AppName: "app-2", Message: "Is required"}}},
+	})
+	if err == nil {
+		t.Fatal("Expected error")
+	}
+	if !reflect.DeepEqual(c.apps, lastApps) || c.settings != settings || c.lastRepositorySha != "revision-1" || len(c.AppErrors()) != 0 {
+		t.Fatalf("Expected rejected revision not to change anything, got apps %+v, settings %+v, revision %s", c.apps, c.settings, c.lastRepositorySha)
+	}
+
+	// Apps didn't change, so nothing is sent to the container manager
+	err = c.applyConfiguration("revision-3", loadedConfiguration{settings: changedSettings, apps: lastApps})
+	if err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	if c.settings != changedSettings || c.lastRepositorySha != "revision-3" {
+		t.Fatalf("Expected valid revision to be applied, got settings %+v, revision %s", c.settings, c.lastRepositorySha)
+	}
+}
@@ -0,0 +1,92 @@
+package configuration
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Dependencies have to exist and must not form a cycle.
+// knownAppNames are names of all app files, including the invalid and disabled ones
+func checkDependencies(files []appConfigurationFile, knownAppNames []string) []ConfigurationError {
+	var configurationErrors []ConfigurationError
+
+	filesByName := make(map[string]appConfigurationFile, len(files))
+	for _, file := range files {
+		filesByName[file.appName] = file
+	}
+
+	for _, file := range files {
+		for i, dependency := range file.configuration.DependsOn {
+			if !slices.Contains(knownAppNames, dependency) {
+				configurationErrors = append(configurationErrors, ConfigurationError{
+					File:    file.filePath,
+					AppName: file.appName,
+					Field:   fmt.Sprintf("dependsOn[%d]", i),
+					Message: fmt.Sprintf("App `%s` doesn't exist", dependency),
+				})
+			}
+		}
+	}
+
+	for _, cycle := range findDependencyCycles(files) {
+		for _, appName := range cycle[:len(cycle)-1] {
+			file := filesByName[appName]
+			configurationErrors = append(configurationErrors, ConfigurationError{
+				File:    file.filePath,
+				AppName: file.appName,
+				Field:   "dependsOn",
+				Message: fmt.Sprintf("Dependencies form a cycle: %s", strings.Join(cycle, " -> ")),
+			})
+		}
+	}
+
+	return configurationErrors
+}
+
+// Every cycle is returned once, starting and ending with the same app
+func findDependencyCycles(files []appConfigurationFile) [][]string {
+	dependencies := make(map[string][]string, len(files))
+	for _, file := range files {
+		dependencies[file.appName] = file.configuration.DependsOn
+	}
+
+	const (
+		unvisited = iota
+		inProgress
+		done
+	)
+	states := make(map[string]int, len(files))
+	var path []string
+	var cycles [][]string
+
+	var visit func(appName string)
+	visit = func(appName string) {
+		states[appName] = inProgress
+		path = append(path, appName)
+
+		for _, dependency := range dependencies[appName] {
+			switch states[dependency] {
+			case unvisited:
+				if _, ok := dependencies[dependency]; ok {
+					visit(dependency)
+				}
+			case inProgress:
+				start := slices.Index(path, dependency)
+				cycle := slices.Clone(path[start:])
+				cycles = append(cycles, append(cycle, dependency))
+			}
+		}
+
+		path = path[:len(path)-1]
+		states[appName] = done
+	}
+
+	for _, file := range files {
+		if states[file.appName] == unvisited {
+			visit(file.appName)
+		}
+	}
+
+	return cycles
+}
@@ -0,0 +1,29 @@
+package configuration
+
+import (
+	"path"
+	"reflect"
+	"testing"
+)
+
+func TestReadAppConfigurationFiles_Dependencies(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, path.Join(root, "apps", "db.yaml"), "version: 1\nsource:\n  github: {owner: a, repository: db, revision: main}\n")
+	writeTestFile(t, path.Join(root, "apps", "api.yaml"), "version: 1\ndependsOn: [db, cache]\nsource:\n  github: {owner: a, repository: api, revision: main}\n")
+	writeTestFile(t, path.Join(root, "apps", "a.yaml"), "version: 1\ndependsOn: [b]\nsource:\n  github: {owner: a, repository: a, revision: main}\n")
+	writeTestFile(t, path.Join(root, "apps", "b.yaml"), "version: 1\ndependsOn: [a]\nsource:\n  github: {owner: a, repository: b, revision: main}\n")
+
+	files, configurationErrors := readAppConfigurationFiles(root, defaultAppsConfigurationDir, "", nil)
+
+	expected := []ConfigurationError{
+		{File: "apps/api.yaml", AppName: "api", Field: "dependsOn[1]", Message: "App `cache` doesn't exist"},
+		{File: "apps/a.yaml", AppName: "a", Field: "dependsOn", Message: "Dependencies form a cycle: a -> b -> a"},
+		{File: "apps/b.yaml", AppName: "b", Field: "dependsOn", Message: "Dependencies form a cycle: a -> b -> a"},
+	}
+	if !reflect.DeepEqual(configurationErrors, expected) {
+		t.Fatalf("Expected %+v, got %+v", expected, configurationErrors)
+	}
+	if len(files) != 1 || files[0].appName != "db" {
+		t.Fatalf("Expected only app `db`, got %+v", files)
+	}
+}
@@ -0,0 +1,65 @@
+package configuration
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strings"
+)
+
+// Overlays of environment `<name>` are in `<environmentsDir>/<name>/<app name>.yaml`
+const environmentsDir = "environments"
+
+// Key is the app name, value is the path of the overlay file relative to the configuration root
+type environmentOverlays map[string]string
+
+// environment empty = no overlays. Missing environment directory is an error, so a typo in the environment name doesn't go unnoticed
+func readEnvironmentOverlays(root string, environment string) (environmentOverlays, []ConfigurationError) {
+	if environment == "" {
+		return nil, nil
+	}
+
+	environmentDir := path.Join(environmentsDir, environment)
+	entries, err := os.ReadDir(path.Join(root, environmentDir))
+	if err != nil {
+		return nil, []ConfigurationError{{File: environmentDir, Message: fmt.Sprintf("Failed to read environment `%s`: %s", environment, err.Error())}}
+	}
+
+	overlays := environmentOverlays{}
+	var configurationErrors []ConfigurationError
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+
+		filePath := path.Join(environmentDir, entry.Name())
+		appName := getConfigurationName(filePath)
+		if _, ok := overlays[appName]; ok {
+			configurationErrors = append(configurationErrors, ConfigurationError{File: filePath, Message: fmt.Sprintf("There are multiple overlays of app `%s`", appName)})
+			continue
+		}
+		overlays[appName] = filePath
+	}
+
+	return overlays, configurationErrors
+}
+
+// Overlay without an app is most likely a typo in its name
+func checkOverlaysHaveApps(overlays environmentOverlays, appNames []string) []ConfigurationError {
+	var configurationErrors []ConfigurationError
+	for appName, filePath := range overlays {
+		if !slices.Contains(appNames, appName) {
+			configurationErrors = append(configurationErrors, ConfigurationError{
+				File:    filePath,
+				AppName: appName,
+				Message: fmt.Sprintf("App `%s` doesn't exist", appName),
+			})
+		}
+	}
+
+	slices.SortFunc(configurationErrors, func(a ConfigurationError, b ConfigurationError) int {
+		return strings.Compare(a.File, b.File)
+	})
+	return configurationErrors
+}
@@ -0,0 +1,47 @@
+package configuration
+
+import (
+	"path"
+	"reflect"
+	"testing"
+)
+
+func TestReadAppConfigurationFiles_AppliesEnvironmentOverlays(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, path.Join(root, "apps", "api.yaml"), "version: 1\nsource:\n  github: {owner: a, repository: api, revision: main}\nruntime:\n  env: {LOG_LEVEL: info}\n")
+	writeTestFile(t, path.Join(root, "apps", "worker.yaml"), "version: 1\nsource:\n  github: {owner: a, repository: worker, revision: main}\n")
+	writeTestFile(t, path.Join(root, "environments", "staging", "api.yaml"), "source:\n  github: {revision: develop}\nruntime:\n  env: {LOG_LEVEL: debug}\n  replicas: 3\n")
+	writeTestFile(t, path.Join(root, "environments", "staging", "worker.yaml"), "enabled: false\n")
+
+	files, configurationErrors := readAppConfigurationFiles(root, defaultAppsConfigurationDir, "staging", nil)
+	if len(configurationErrors) > 0 {
+		t.Fatalf("Expected no errors, got %+v", configurationErrors)
+	}
+	if len(files) != 2 || files[0].appName != "api" || files[1].appName != "worker" {
+		t.Fatalf("Expected apps `api` and `worker`, got %+v", files)
+	}
+	if files[0].configuration.isPaused() || !files[1].configuration.isPaused() {
+		t.Fatalf("Expected only app `worker` to be paused, got %+v", files)
+	}
+	if files[0].configuration.Source.Github.Revision != "develop" || files[0].configuration.Runtime.Env["LOG_LEVEL"] != "debug" || files[0].configuration.Runtime.Replicas != 3 {
+		t.Fatalf("Expected overlay to be applied, got %+v", files[0].configuration)
+	}
+
+	files, _ = readAppConfigurationFiles(root, defaultAppsConfigurationDir, "", nil)
+	if len(files) != 2 || files[0].configuration.Source.Github.Revision != "main" || files[0].configuration.Runtime.Replicas != 0 {
+		t.Fatalf("Expected base configuration without environment, got %+v", files)
+	}
+}
+
+func TestReadAppConfigurationFiles_OverlayWithoutApp(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, path.Join(root, "apps", "api.yaml"), "version: 1\nsource:\n  github: {owner: a, repository: api, revision: main}\n")
+	writeTestFile(t, path.Join(root, "environments", "staging", "apii.yaml"), "enabled: false\n")
+
+	_, configurationErrors := readAppConfigurationFiles(root, defaultAppsConfigurationDir, "staging", nil)
+
+	expected := []ConfigurationError{{File: "environments/staging/apii.yaml", AppName: "apii", Message: "App `apii` doesn't exist"}}
+	if !reflect.DeepEqual(configurationErrors, expected) {
+		t.Fatalf("Expected %+v, got %+v", expected, configurationErrors)
+	}
+}
@@ -0,0 +1,200 @@
+package configuration
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var commitShaRegex = regexp.MustCompile("^[0-9a-f]{40}$")
+
+// Commit sha or ref name. Can't start with `-`, so git doesn't take it for an option
+var revisionRegex = regexp.MustCompile("^[A-Za-z0-9_][A-Za-z0-9._/-]*$")
+
+// Revisions from the outside (e.g. the HTTP API) are passed to git, which would run commands given as options
+func ValidateRevision(revision string) error {
+	if !revisionRegex.MatchString(revision) || strings.Contains(revision, "..") || strings.HasSuffix(revision, "/") {
+		return fmt.Errorf("Revision `%s` isn't a commit sha nor a ref name", revision)
+	}
+	return nil
+}
+
+// Configuration in any git remote (ssh, https or file URL). Uses the git binary
+type GitSource struct {
+	url string
+	// Branch, tag, full ref name or commit sha
+	ref string
+	// nil = default ssh configuration
+	sshKeyFile *string
+	// Host keys of ssh remotes, unknown hosts are rejected. nil = default ssh configuration
+	knownHostsFile *string
+	cloneDir       string
+}
+
+func NewGitSource(url string, ref string, sshKeyFile *string, knownHostsFile *string, managedStoragePath string) GitSource {
+	const cloneDir = "configuration-git"
+
+	return GitSource{
+		url:            url,
+		ref:            ref,
+		sshKeyFile:     sshKeyFile,
+		knownHostsFile: knownHostsFile,
+		cloneDir:       path.Join(managedStoragePath, cloneDir),
+	}
+}
+
+// Revision is sha of the commit the ref points to
+func (g GitSource) GetRevision(ctx context.Context) (string, error) {
+	if commitShaRegex.MatchString(g.ref) {
+		return g.ref, nil
+	}
+
+	stdout, err := g.runGit(ctx, "ls-remote", g.url, g.ref, g.ref+"^{}")
+	if err != nil {
+		return "", err
+	}
+
+	refs := map[string]string{}
+	for _, line := range strings.Split(strings.TrimSpace(stdout), "\n") {
+		sha, name, ok := strings.Cut(line, "\t")
+		if ok {
+			refs[name] = sha
+		}
+	}
+
+	// Peeled tag points to the commit instead of the tag object
+	candidates := []string{
+		g.ref,
+		"refs/heads/" + g.ref,
+		"refs/tags/" + g.ref + "^{}",
+		"refs/tags/" + g.ref,
+	}
+	for _, candidate := range candidates {
+		if sha, ok := refs[candidate]; ok {
+			return sha, nil
+		}
+	}
+
+	return "", fmt.Errorf("Ref `%s` not found in `%s`", g.ref, g.url)
+}
+
+func (g GitSource) Checkout(ctx context.Context, revision string) (string, error) {
+	err := ValidateRevision(revision)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := os.Stat(path.Join(g.cloneDir, ".git")); err != nil {
+		err = os.MkdirAll(g.cloneDir, 0755)
+		if err != nil {
+			return "", err
+		}
+		_, err = g.runGit(ctx, "-C", g.cloneDir, "init", "--quiet")
+		if err != nil {
+			return "", err
+		}
+		_, err = g.runGit(ctx, "-C", g.cloneDir, "remote", "add", "origin", g.url)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// The URL may have changed since the clone was created
+	_, err = g.runGit(ctx, "-C", g.cloneDir, "remote", "set-url", "origin", g.url)
+	if err != nil {
+		return "", err
+	}
+
+	// Not every server allows fetching commits by sha, the ref contains the commit unless it moved in the meantime
+	_, err = g.runGit(ctx, "-C", g.cloneDir, "fetch", "--quiet", "--depth", "1", "--end-of-options", "origin", revision)
+	if err != nil {
+		_, err = g.runGit(ctx, "-C", g.cloneDir, "fetch", "--quiet", "--end-of-options", "origin", g.ref)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// Checkout of older git versions doesn't accept `--end-of-options` together with `--detach`, the resolved sha is safe
+	stdout, err := g.runGit(ctx, "-C", g.cloneDir, "rev-parse", "--verify", "--end-of-options", revision+"^{commit}")
+	if err != nil {
+		return "", err
+	}
+	_, err = g.runGit(ctx, "-C", g.cloneDir, "checkout", "--quiet", "--force", "--detach", strings.TrimSpace(stdout))
+	if err != nil {
+		return "", err
+	}
+	_, err = g.runGit(ctx, "-C", g.cloneDir, "clean", "--quiet", "-ffdx")
+	if err != nil {
+		return "", err
+	}
+
+	return g.cloneDir, nil
+}
+
+func (g GitSource) runGit(ctx context.Context, args ...string) (string, error) {
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// Git must never wait for credentials on the terminal
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if g.sshKeyFile != nil || g.knownHostsFile != nil {
+		sshCommand, err := g.getSshCommand()
+		if err != nil {
+			return "", err
+		}
+		cmd.Env = append(cmd.Env, "GIT_SSH_COMMAND="+sshCommand)
+	}
+
+	err := cmd.Run()
+	if err != nil {
+		return "", fmt.Errorf("Git command `git %s` failed: %w\nstderr=%s", args[0], err, stderr.String())
+	}
+
+	return stdout.String(), nil
+}
+
+// Git runs the command by the shell, and ssh parses values of the options as its configuration
+func (g GitSource) getSshCommand() (string, error) {
+	args := []string{"ssh", "-o", "BatchMode=yes"}
+	if g.sshKeyFile != nil {
+		identityFileOption, err := getSshOption("IdentityFile", *g.sshKeyFile)
+		if err != nil {
+			return "", err
+		}
+		args = append(args, "-o", identityFileOption, "-o", "IdentitiesOnly=yes")
+	}
+	if g.knownHostsFile != nil {
+		knownHostsOption, err := getSshOption("UserKnownHostsFile", *g.knownHostsFile)
+		if err != nil {
+			return "", err
+		}
+		args = append(args, "-o", knownHostsOption, "-o", "StrictHostKeyChecking=yes")
+	}
+
+	quoted := make([]string, 0, len(args))
+	for _, arg := range args {
+		quoted = append(quoted, quoteShellArg(arg))
+	}
+	return strings.Join(quoted, " "), nil
+}
+
+// Value is quoted, so it can contain spaces, and `%` is escaped, so ssh doesn't expand it as a token.
+// The ssh configuration can't escape `"` inside of quotes
+func getSshOption(name string, filePath string) (string, error) {
+	if strings.ContainsAny(filePath, "\"\n") {
+		return "", fmt.Errorf("Path `%s` of ssh option `%s` can't contain `\"` nor a new line", filePath, name)
+	}
+	return fmt.Sprintf(`%s="%s"`, name, strings.ReplaceAll(filePath, "%", "%%")), nil
+}
+
+func quoteShellArg(arg string) string {
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
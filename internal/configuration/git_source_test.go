@@ -0,0 +1,169 @@
+package configuration
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGitSource_FileRemote(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	remoteDir := t.TempDir()
+	runTestGit(t, remoteDir, "init", "--quiet", "--initial-branch", "main")
+	writeTestFile(t, path.Join(remoteDir, "apps", "app.yaml"), "version: 1")
+	runTestGit(t, remoteDir, "add", ".")
+	runTestGit(t, remoteDir, "commit", "--quiet", "-m", "First")
+	firstSha := runTestGit(t, remoteDir, "rev-parse", "HEAD")
+	runTestGit(t, remoteDir, "tag", "-a", "v1", "-m", "v1")
+
+	ctx := context.Background()
+	source := NewGitSource("file://"+remoteDir, "main", nil, nil, t.TempDir())
+
+	revision, err := source.GetRevision(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revision != firstSha {
+		t.Fatalf("Expected revision %s, got %s", firstSha, revision)
+	}
+
+	writeTestFile(t, path.Join(remoteDir, "apps", "app.yaml"), "version: 2")
+	runTestGit(t, remoteDir, "commit", "--quiet", "-am", "Second")
+	secondSha := runTestGit(t, remoteDir, "rev-parse", "HEAD")
+
+	revision, err = source.GetRevision(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revision != secondSha {
+		t.Fatalf("Expected revision %s, got %s", secondSha, revision)
+	}
+
+	checkoutDir, err := source.Checkout(ctx, revision)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(path.Join(checkoutDir, "apps", "app.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "version: 2" {
+		t.Fatalf("Expected checked out content of the second commit, got `%s`", content)
+	}
+
+	tagSource := NewGitSource("file://"+remoteDir, "v1", nil, nil, t.TempDir())
+	revision, err = tagSource.GetRevision(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revision != firstSha {
+		t.Fatalf("Expected tag to resolve to commit %s, got %s", firstSha, revision)
+	}
+}
+
+func TestGitSource_RejectsOptionRevision(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	remoteDir := t.TempDir()
+	runTestGit(t, remoteDir, "init", "--quiet", "--initial-branch", "main")
+	writeTestFile(t, path.Join(remoteDir, "apps", "app.yaml"), "version: 1")
+	runTestGit(t, remoteDir, "add", ".")
+	runTestGit(t, remoteDir, "commit", "--quiet", "-m", "First")
+
+	marker := path.Join(t.TempDir(), "marker")
+	source := NewGitSource("file://"+remoteDir, "main", nil, nil, t.TempDir())
+	_, err := source.Checkout(context.Background(), "--upload-pack=touch "+marker+"; git-upload-pack")
+	if err == nil {
+		t.Fatal("Expected error")
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Fatal("Command from the revision was run")
+	}
+}
+
+func TestValidateRevision(t *testing.T) {
+	tests := []struct {
+		revision string
+		valid    bool
+	}{
+		{"main", true},
+		{"refs/heads/feature/x", true},
+		{"v1.2.3", true},
+		{"0123456789abcdef0123456789abcdef01234567", true},
+		{"--upload-pack=touch /tmp/x", false},
+		{"-b", false},
+		{"main..other", false},
+		{"main; rm -rf /", false},
+		{"", false},
+	}
+
+	for _, test := range tests {
+		err := ValidateRevision(test.revision)
+		if (err == nil) != test.valid {
+			t.Fatalf("Expected validity %t for `%s`, got %v", test.valid, test.revision, err)
+		}
+	}
+}
+
+func TestGitSource_SshCommand(t *testing.T) {
+	sshKeyFile := "/keys/it's a key%h"
+	knownHostsFile := "/etc/known hosts"
+	source := NewGitSource("git@example.com:config.git", "main", &sshKeyFile, &knownHostsFile, t.TempDir())
+
+	sshCommand, err := source.getSshCommand()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The way git runs it
+	output, err := exec.Command("sh", "-c", "printf '%s\\n' "+strings.TrimPrefix(sshCommand, "'ssh' ")).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"-o", "BatchMode=yes",
+		"-o", `IdentityFile="/keys/it's a key%%h"`,
+		"-o", "IdentitiesOnly=yes",
+		"-o", `UserKnownHostsFile="/etc/known hosts"`,
+		"-o", "StrictHostKeyChecking=yes",
+	}
+	if res := strings.Split(strings.TrimSuffix(string(output), "\n"), "\n"); !reflect.DeepEqual(res, expected) {
+		t.Fatalf("Expected arguments %q, got %q", expected, res)
+	}
+}
+
+func TestGitSource_SshCommandRejectsQuote(t *testing.T) {
+	sshKeyFile := `/keys/"key`
+	source := NewGitSource("git@example.com:config.git", "main", &sshKeyFile, nil, t.TempDir())
+
+	if _, err := source.getSshCommand(); err == nil {
+		t.Fatal("Expected error")
+	}
+}
+
+func runTestGit(t *testing.T, dir string, args ...string) string {
+	cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v failed: %s %s", args, err, output)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+func writeTestFile(t *testing.T, filePath string, content string) {
+	if err := os.MkdirAll(path.Dir(filePath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
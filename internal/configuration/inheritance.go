@@ -0,0 +1,203 @@
+package configuration
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Files in the apps directory starting with this prefix aren't apps. YAML files among them are shared parts of app configurations,
+// the rest is ignored, e.g. `_README.md`
+const baseFilePrefix = "_"
+
+// Merged under every app configuration
+const defaultsBaseName = "defaults"
+
+// Names of the profiles the app configuration is merged with
+const extendsKey = "extends"
+
+// App configuration file, profile, or environment overlay
+type appConfigurationDocument struct {
+	appConfiguration `yaml:",inline"`
+	// Allowed only in apps, checked by the callers
+	Extends any
+}
+
+// Shared parts of app configurations
+type appConfigurationBases struct {
+	// nil = no defaults
+	defaults map[string]any
+	// Key is the profile name, e.g. `web` for `_web.yaml`
+	profiles map[string]map[string]any
+}
+
+func isBaseFile(fileName string) bool {
+	extension := path.Ext(fileName)
+	return strings.HasPrefix(fileName, baseFilePrefix) && (extension == ".yaml" || extension == ".yml")
+}
+
+func isAppFile(fileName string) bool {
+	return !strings.HasPrefix(fileName, baseFilePrefix)
+}
+
+// Name of the app or profile the file defines
+func getConfigurationName(filePath string) string {
+	return strings.Split(strings.TrimPrefix(path.Base(filePath), baseFilePrefix), ".")[0]
+}
+
+// Errors of base files affect all apps, so they don't belong to any app
+func readAppConfigurationBases(root string, appsDir string, entries []os.DirEntry) (appConfigurationBases, []ConfigurationError) {
+	bases := appConfigurationBases{profiles: map[string]map[string]any{}}
+
+	var configurationErrors []ConfigurationError
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !isBaseFile(entry.Name()) {
+			continue
+		}
+
+		filePath := path.Join(appsDir, entry.Name())
+		document, documentErrors := readYamlDocument(root, filePath)
+		if len(documentErrors) > 0 {
+			configurationErrors = append(configurationErrors, documentErrors...)
+			continue
+		}
+		if _, ok := document[extendsKey]; ok {
+			configurationErrors = append(configurationErrors, ConfigurationError{File: filePath, Field: extendsKey, Message: "Only apps can extend profiles"})
+			continue
+		}
+
+		name := getConfigurationName(filePath)
+		if name == defaultsBaseName {
+			bases.defaults = document
+		} else {
+			bases.profiles[name] = document
+		}
+	}
+
+	return bases, configurationErrors
+}
+
+// Merges the app document over the defaults and the profiles it extends, in the listed order
+func (b appConfigurationBases) apply(document map[string]any) (map[string]any, error) {
+	profileNames, err := getExtends(document)
+	if err != nil {
+		return nil, err
+	}
+
+	res := mergeYamlMaps(nil, b.defaults)
+	for _, profileName := range profileNames {
+		profile, ok := b.profiles[profileName]
+		if !ok {
+			return nil, fmt.Errorf("Profile `%s` doesn't exist. It should be defined in `%s%s.yaml`", profileName, baseFilePrefix, profileName)
+		}
+		res = mergeYamlMaps(res, profile)
+	}
+	res = mergeYamlMaps(res, document)
+	delete(res, extendsKey)
+
+	return res, nil
+}
+
+// `extends` can be a single profile name or a list of them
+func getExtends(document map[string]any) ([]string, error) {
+	switch extends := document[extendsKey].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{extends}, nil
+	case []any:
+		profileNames := make([]string, 0, len(extends))
+		for _, item := range extends {
+			profileName, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("Must be a profile name or a list of profile names")
+			}
+			profileNames = append(profileNames, profileName)
+		}
+		return profileNames, nil
+	default:
+		return nil, fmt.Errorf("Must be a profile name or a list of profile names")
+	}
+}
+
+// Maps are merged recursively, everything else in override replaces the value in base. Inputs aren't modified
+func mergeYamlMaps(base map[string]any, override map[string]any) map[string]any {
+	res := make(map[string]any, len(base)+len(override))
+	for key, value := range base {
+		res[key] = value
+	}
+
+	for key, overrideValue := range override {
+		baseMap, baseIsMap := res[key].(map[string]any)
+		overrideMap, overrideIsMap := overrideValue.(map[string]any)
+		if baseIsMap && overrideIsMap {
+			res[key] = mergeYamlMaps(baseMap, overrideMap)
+			continue
+		}
+		res[key] = overrideValue
+	}
+
+	return res
+}
+
+// filePath is relative to root. Empty document is returned as empty map. Every document is decoded on its own before it's merged,
+// so decode errors cite lines of the file they are in. App name of the errors is left empty
+func readYamlDocument(root string, filePath string) (map[string]any, []ConfigurationError) {
+	content, err := os.ReadFile(path.Join(root, filePath))
+	if err != nil {
+		return nil, []ConfigurationError{{File: filePath, Message: err.Error()}}
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	err = decoder.Decode(&appConfigurationDocument{})
+	if err != nil && err != io.EOF {
+		configurationErrors := getDecodeErrors(err)
+		for i := range configurationErrors {
+			configurationErrors[i].File = filePath
+		}
+		return nil, configurationErrors
+	}
+
+	document := map[string]any{}
+	err = yaml.Unmarshal(content, &document)
+	if err != nil {
+		return nil, []ConfigurationError{{File: filePath, Message: fmt.Sprintf("Failed to decode: %s", err.Error())}}
+	}
+	return document, nil
+}
+
+// Returns the app configuration merged with the defaults, the profiles it extends and the environment overlay, encoded as YAML.
+// root is the root of the configuration repository, environment empty = no environment overlays
+func GetEffectiveAppConfiguration(root string, appName string, environment string) ([]byte, error) {
+	entries, err := os.ReadDir(path.Join(root, defaultAppsConfigurationDir))
+	if err != nil {
+		return nil, err
+	}
+
+	bases, configurationErrors := readAppConfigurationBases(root, defaultAppsConfigurationDir, entries)
+	overlays, overlaysErrors := readEnvironmentOverlays(root, environment)
+	configurationErrors = append(configurationErrors, overlaysErrors...)
+	if len(configurationErrors) > 0 {
+		return nil, configurationErrors[0]
+	}
+
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !isAppFile(entry.Name()) || getConfigurationName(entry.Name()) != appName {
+			continue
+		}
+
+		effective, configurationErrors := readEffectiveAppConfiguration(root, path.Join(defaultAppsConfigurationDir, entry.Name()), bases, overlays)
+		if len(configurationErrors) > 0 {
+			return nil, configurationErrors[0]
+		}
+		return yaml.Marshal(effective)
+	}
+
+	return nil, fmt.Errorf("App `%s` doesn't exist", appName)
+}
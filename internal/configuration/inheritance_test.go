@@ -0,0 +1,78 @@
+package configuration
+
+import (
+	"path"
+	"reflect"
+	"testing"
+)
+
+func TestReadAppConfigurationFiles_MergesDefaultsAndProfiles(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, path.Join(root, "apps", "_defaults.yaml"), "version: 1\nsource:\n  github: {owner: acme, revision: main}\nruntime:\n  env: {LOG_LEVEL: info, REGION: eu}\n")
+	writeTestFile(t, path.Join(root, "apps", "_web.yaml"), "runtime:\n  env: {LOG_LEVEL: debug}\n  routes: [{port: 80, url: default.example.com}]\n")
+	writeTestFile(t, path.Join(root, "apps", "api.yaml"), "extends: web\nsource:\n  github: {repository: api}\nruntime:\n  routes: [{port: 8080, url: api.example.com}]\n")
+
+	files, configurationErrors := readAppConfigurationFiles(root, defaultAppsConfigurationDir, "", nil)
+	if len(configurationErrors) > 0 {
+		t.Fatalf("Expected no errors, got %+v", configurationErrors)
+	}
+	if len(files) != 1 {
+		t.Fatalf("Expected only the app file, got %+v", files)
+	}
+
+	decoded := files[0].configuration
+	if decoded.Source.Github.Owner != "acme" || decoded.Source.Github.Repository != "api" || decoded.Source.Github.Revision != "main" {
+		t.Fatalf("Unexpected source %+v", decoded.Source)
+	}
+	expectedEnv := map[string]string{"LOG_LEVEL": "debug", "REGION": "eu"}
+	if !reflect.DeepEqual(decoded.Runtime.Env, expectedEnv) {
+		t.Fatalf("Expected env %+v, got %+v", expectedEnv, decoded.Runtime.Env)
+	}
+	if len(decoded.Runtime.Routes) != 1 || decoded.Runtime.Routes[0].Url != "api.example.com" {
+		t.Fatalf("Expected routes of the app to replace inherited ones, got %+v", decoded.Runtime.Routes)
+	}
+}
+
+func TestReadAppConfigurationFiles_UnknownProfile(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, path.Join(root, "apps", "api.yaml"), "extends: [missing]\nversion: 1\nsource:\n  github: {owner: a, repository: b, revision: c}\n")
+
+	_, configurationErrors := readAppConfigurationFiles(root, defaultAppsConfigurationDir, "", nil)
+
+	expected := []ConfigurationError{{
+		File:    "apps/api.yaml",
+		AppName: "api",
+		Field:   "extends",
+		Message: "Profile `missing` doesn't exist. It should be defined in `_missing.yaml`",
+	}}
+	if !reflect.DeepEqual(configurationErrors, expected) {
+		t.Fatalf("Expected %+v, got %+v", expected, configurationErrors)
+	}
+}
+
+func TestReadAppConfigurationFiles_ErrorsCiteLinesOfProfile(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, path.Join(root, "apps", "_web.yaml"), "runtime:\n  env: {LOG_LEVEL: debug}\n  rotues: []\n")
+	writeTestFile(t, path.Join(root, "apps", "api.yaml"), "extends: web\nversion: 1\nsource:\n  github: {owner: a, repository: b, revision: c}\n")
+
+	_, configurationErrors := readAppConfigurationFiles(root, defaultAppsConfigurationDir, "", nil)
+
+	expected := []ConfigurationError{{File: "apps/_web.yaml", Message: "Failed to decode: line 3: unknown field `rotues`"}}
+	if !reflect.DeepEqual(configurationErrors, expected) {
+		t.Fatalf("Expected %+v, got %+v", expected, configurationErrors)
+	}
+}
+
+func TestReadAppConfigurationFiles_IgnoresOtherBaseFiles(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, path.Join(root, "apps", "_README.md"), "# Apps\n\nShared settings are in `_defaults.yaml`.\n")
+	writeTestFile(t, path.Join(root, "apps", "api.yaml"), "version: 1\nsource:\n  github: {owner: a, repository: b, revision: c}\n")
+
+	files, configurationErrors := readAppConfigurationFiles(root, defaultAppsConfigurationDir, "", nil)
+	if len(configurationErrors) > 0 {
+		t.Fatalf("Expected no errors, got %+v", configurationErrors)
+	}
+	if len(files) != 1 || files[0].appName != "api" {
+		t.Fatalf("Expected only the app file, got %+v", files)
+	}
+}
@@ -0,0 +1,72 @@
+package configuration
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Configuration in a directory on the same host. Useful for hosts without access to Github and for local development
+type LocalSource struct {
+	dir string
+}
+
+func NewLocalSource(dir string) LocalSource {
+	return LocalSource{dir: dir}
+}
+
+// Revision is hash of paths and contents of all files in the directory
+func (l LocalSource) GetRevision(ctx context.Context) (string, error) {
+	hash := sha256.New()
+
+	// WalkDir goes through the entries in lexical order, so the hash is stable
+	err := filepath.WalkDir(l.dir, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if entry.IsDir() && entry.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		relativePath, err := filepath.Rel(l.dir, filePath)
+		if err != nil {
+			return err
+		}
+
+		file, err := os.Open(filePath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		fileHash := sha256.New()
+		_, err = io.Copy(fileHash, file)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(hash, "%s\x00%x\n", relativePath, fileHash.Sum(nil))
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
+
+// The directory is used directly
+func (l LocalSource) Checkout(ctx context.Context, revision string) (string, error) {
+	return l.dir, nil
+}
@@ -0,0 +1,43 @@
+package configuration
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestLocalSource_RevisionChangesWithContent(t *testing.T) {
+	dir := t.TempDir()
+	appsDir := path.Join(dir, "apps")
+	if err := os.Mkdir(appsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path.Join(appsDir, "app.yaml"), []byte("version: 1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	source := NewLocalSource(dir)
+	first, err := source.GetRevision(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := source.GetRevision(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second {
+		t.Fatal("Expected the same revision for unchanged content")
+	}
+
+	if err := os.WriteFile(path.Join(appsDir, "app.yaml"), []byte("version: 2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	third, err := source.GetRevision(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == third {
+		t.Fatal("Expected different revision for changed content")
+	}
+}
@@ -0,0 +1,180 @@
+package configuration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/krystofrezac/lifebuoy/internal/apps"
+)
+
+// Runtime overrides of `enabled`/`paused` from the app configuration, in managed storage so they survive restart
+const pauseOverridesFile = "paused-apps.json"
+
+// By app name. true = paused, false = running even when the configuration pauses the app
+type pauseOverrides map[string]bool
+
+// Missing file = no overrides
+func readPauseOverrides(managedStoragePath string) (pauseOverrides, error) {
+	content, err := os.ReadFile(filepath.Join(managedStoragePath, pauseOverridesFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return pauseOverrides{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	overrides := pauseOverrides{}
+	err = json.Unmarshal(content, &overrides)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse `%s`: %w", pauseOverridesFile, err)
+	}
+	return overrides, nil
+}
+
+// The file is replaced only when the whole content is written
+func savePauseOverrides(managedStoragePath string, overrides pauseOverrides) error {
+	content, err := json.MarshalIndent(overrides, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	target := filepath.Join(managedStoragePath, pauseOverridesFile)
+	temporary := target + ".new"
+	err = os.WriteFile(temporary, content, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(temporary, target)
+}
+
+// Sorted names of apps paused by the configuration or by an override
+func getPausedAppNames(configurationPausedAppNames []string, overrides pauseOverrides) []string {
+	var res []string
+	for _, appName := range configurationPausedAppNames {
+		if paused, ok := overrides[appName]; !ok || paused {
+			res = append(res, appName)
+		}
+	}
+	for appName, paused := range overrides {
+		if paused && !slices.Contains(res, appName) {
+			res = append(res, appName)
+		}
+	}
+	slices.Sort(res)
+	return res
+}
+
+type pauseRequest struct {
+	ctx     context.Context
+	appName string
+	// nil = remove the override
+	paused        *bool
+	resultChannel chan<- pauseResult
+}
+
+type pauseResult struct {
+	paused bool
+	err    error
+}
+
+// Overrides whether the app is paused, regardless of the configuration. paused nil = the configuration decides again.
+// Returns whether the app is paused now
+func (c *ConfigurationManager) SetAppPaused(ctx context.Context, appName string, paused *bool) (bool, error) {
+	resultChannel := make(chan pauseResult, 1)
+	select {
+	case c.pauseRequestChannel <- pauseRequest{ctx: ctx, appName: appName, paused: paused, resultChannel: resultChannel}:
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+
+	select {
+	case result := <-resultChannel:
+		return result.paused, result.err
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
+
+func (c *ConfigurationManager) setAppPaused(ctx context.Context, appName string, paused *bool) (bool, error) {
+	if c.apps == nil {
+		return false, fmt.Errorf("Configuration isn't loaded yet")
+	}
+	if !slices.ContainsFunc(c.apps, func(app apps.App) bool { return app.Configuration().AppName == appName }) {
+		return false, fmt.Errorf("App `%s` doesn't exist", appName)
+	}
+
+	overrides := maps.Clone(c.pauseOverrides)
+	if paused == nil {
+		delete(overrides, appName)
+	} else {
+		overrides[appName] = *paused
+	}
+
+	// Secrets and credentials of apps paused by the configuration weren't resolved, the current revision is loaded again with them
+	var resumed *loadedConfiguration
+	if slices.Contains(c.placeholderAppNames, appName) && paused != nil && !*paused {
+		loaded, err := c.loadResumedApp(ctx, appName, overrides)
+		if err != nil {
+			return false, err
+		}
+		resumed = &loaded
+	}
+
+	err := savePauseOverrides(c.managedStoragePath, overrides)
+	if err != nil {
+		return false, fmt.Errorf("Failed to save pause overrides: %w", err)
+	}
+	c.pauseOverrides = overrides
+	c.logger.Info("Pause override of app changed", "appName", appName, "paused", paused)
+
+	if resumed != nil {
+		err = c.applyConfiguration(c.lastRepositorySha, *resumed)
+		if err != nil {
+			return false, err
+		}
+	} else {
+		c.applyPausedApps()
+	}
+	return slices.Contains(c.pausedAppNames, appName), nil
+}
+
+// Loads the current revision as if the overrides were applied. Fails when the app is invalid then, e.g. because its secrets
+// can't be decrypted on this host, so the app stays paused instead of running without them
+func (c *ConfigurationManager) loadResumedApp(ctx context.Context, appName string, overrides pauseOverrides) (loadedConfiguration, error) {
+	configPath, err := c.source.Checkout(ctx, c.lastRepositorySha)
+	if err != nil {
+		return loadedConfiguration{}, fmt.Errorf("Failed to checkout configuration: %w", err)
+	}
+
+	lastOverrides := c.pauseOverrides
+	c.pauseOverrides = overrides
+	loaded, err := c.loadConfiguration(configPath)
+	c.pauseOverrides = lastOverrides
+	if err != nil {
+		return loadedConfiguration{}, err
+	}
+
+	if appErrors := loaded.appErrors[appName]; len(appErrors) > 0 {
+		return loadedConfiguration{}, fmt.Errorf("App `%s` can't be resumed: %w", appName, appErrors[0])
+	}
+	return loaded, checkAppsNameCollisions(loaded.apps)
+}
+
+// Sends the paused apps to the container manager when they changed
+func (c *ConfigurationManager) applyPausedApps() {
+	pausedAppNames := getPausedAppNames(c.configurationPausedAppNames, c.pauseOverrides)
+	if slices.Equal(pausedAppNames, c.pausedAppNames) {
+		return
+	}
+
+	c.pausedAppNames = pausedAppNames
+	c.logger.Info("Paused apps changed", "appNames", pausedAppNames)
+	c.containerManager.UpdateApps(c.apps, apps.Changeset{}, c.invalidAppNames, pausedAppNames)
+}
@@ -0,0 +1,129 @@
+package configuration
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"path"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/client"
+	"github.com/krystofrezac/lifebuoy/internal/apps"
+	"github.com/krystofrezac/lifebuoy/internal/docker"
+	"github.com/krystofrezac/lifebuoy/internal/tarball"
+)
+
+func TestGetPausedAppNames(t *testing.T) {
+	overrides := pauseOverrides{"api": false, "worker": true, "cron": true}
+
+	pausedAppNames := getPausedAppNames([]string{"api", "db"}, overrides)
+
+	expected := []string{"cron", "db", "worker"}
+	if !reflect.DeepEqual(pausedAppNames, expected) {
+		t.Fatalf("Expected %v, got %v", expected, pausedAppNames)
+	}
+}
+
+func TestPauseOverrides_SaveAndRead(t *testing.T) {
+	managedStoragePath := t.TempDir()
+
+	overrides, err := readPauseOverrides(managedStoragePath)
+	if err != nil || len(overrides) != 0 {
+		t.Fatalf("Expected no overrides without file, got %v, %v", overrides, err)
+	}
+
+	expected := pauseOverrides{"api": true, "worker": false}
+	err = savePauseOverrides(managedStoragePath, expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	overrides, err = readPauseOverrides(managedStoragePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(overrides, expected) {
+		t.Fatalf("Expected %v, got %v", expected, overrides)
+	}
+}
+
+func TestReadAppConfigurations_PausedAppsDoNotResolveCredentials(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, path.Join(root, "apps", "app.yaml"), "version: 1\nenabled: false\nsource:\n  github: {owner: a, repository: app, revision: main, credentials: staging}\n")
+
+	c := ConfigurationManager{
+		appsConfigurationDir:      defaultAppsConfigurationDir,
+		repositoryBuildAppCreator: apps.NewRepositoryBuilderAppCreator(slog.New(slog.NewTextHandler(io.Discard, nil)), nil, docker.Docker{}, "", "", tarball.Limits{}),
+		pauseOverrides:            pauseOverrides{},
+	}
+
+	readApps, pausedAppNames, configurationErrors := c.readAppConfigurations(root)
+	if len(configurationErrors) > 0 {
+		t.Fatalf("Expected no errors, got %+v", configurationErrors)
+	}
+	if len(readApps) != 1 || !reflect.DeepEqual(pausedAppNames, []string{"app"}) {
+		t.Fatalf("Expected paused app `app`, got %+v and %+v", readApps, pausedAppNames)
+	}
+
+	c.pauseOverrides["app"] = false
+	_, _, configurationErrors = c.readAppConfigurations(root)
+	if len(configurationErrors) != 1 || configurationErrors[0].Field != "source.github.credentials" {
+		t.Fatalf("Expected error of missing credentials of resumed app, got %+v", configurationErrors)
+	}
+}
+
+func TestSetAppPaused_AppWhoseCredentialsFailStaysPaused(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, path.Join(root, "apps", "app.yaml"), "version: 1\nenabled: false\nsource:\n  github: {owner: a, repository: app, revision: main, credentials: staging}\n")
+
+	settings := getDefaultServerSettings(false)
+	c := ConfigurationManager{
+		logger:                    slog.New(slog.NewTextHandler(io.Discard, nil)),
+		source:                    NewLocalSource(root),
+		managedStoragePath:        t.TempDir(),
+		appsConfigurationDir:      defaultAppsConfigurationDir,
+		repositoryBuildAppCreator: apps.NewRepositoryBuilderAppCreator(slog.New(slog.NewTextHandler(io.Discard, nil)), nil, docker.Docker{}, "", "", tarball.Limits{}),
+		dockefileAppCreator:       apps.NewDockefileAppCreator(nil, &client.Client{}),
+		pauseOverrides:            pauseOverrides{},
+		defaultSettings:           settings,
+		settings:                  settings,
+	}
+
+	// Applied without the container manager
+	loaded, err := c.loadConfiguration(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.apps = loaded.apps
+	c.configurationPausedAppNames = loaded.pausedAppNames
+	c.placeholderAppNames = loaded.placeholderAppNames
+	c.pausedAppNames = getPausedAppNames(loaded.pausedAppNames, c.pauseOverrides)
+	c.lastRepositorySha = "revision"
+
+	resumed := false
+	_, err = c.setAppPaused(context.Background(), "app", &resumed)
+	if err == nil || !strings.Contains(err.Error(), "source.github.credentials") {
+		t.Fatalf("Expected error of missing credentials, got %v", err)
+	}
+
+	overrides, err := readPauseOverrides(c.managedStoragePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(overrides) != 0 || len(c.pauseOverrides) != 0 || !reflect.DeepEqual(c.pausedAppNames, []string{"app"}) {
+		t.Fatalf("Expected app to stay paused without override, got overrides %+v and paused apps %+v", overrides, c.pausedAppNames)
+	}
+
+	// Override saved before the credentials were removed from the host
+	c.pauseOverrides["app"] = false
+	loaded, err = c.loadConfiguration(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slices.ContainsFunc(loaded.apps, func(app apps.App) bool { return app.Configuration().AppName == "app" }) {
+		t.Fatal("Expected definition without credentials not to be used as the known-good one")
+	}
+}
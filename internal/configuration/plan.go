@@ -0,0 +1,70 @@
+package configuration
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/docker/docker/client"
+	"github.com/krystofrezac/lifebuoy/internal/apps"
+	containermanager "github.com/krystofrezac/lifebuoy/internal/container_manager"
+	"github.com/krystofrezac/lifebuoy/internal/docker"
+	"github.com/krystofrezac/lifebuoy/internal/secrets"
+	"github.com/krystofrezac/lifebuoy/internal/sources"
+	"github.com/krystofrezac/lifebuoy/internal/tarball"
+)
+
+// What applying a configuration revision would change
+type RevisionPlan struct {
+	// Empty for local configuration
+	Revision  string                          `json:"revision"`
+	Plan      containermanager.Plan           `json:"plan"`
+	AppErrors map[string][]ConfigurationError `json:"appErrors"`
+}
+
+type LocalPlanOpts struct {
+	// Empty = no environment overlays
+	Environment           string
+	AllowedBindMountPaths []string
+	// nil = apps with secrets are invalid
+	SecretsKey     *secrets.PrivateKey
+	Credentials    sources.Credentials
+	ResourcePrefix string
+}
+
+// Plans configuration from a local directory against the Docker daemon, the same way the server does.
+// root is the root of the configuration repository
+func PlanLocal(ctx context.Context, logger *slog.Logger, dockerClient *client.Client, root string, opts LocalPlanOpts) (RevisionPlan, error) {
+	c := &ConfigurationManager{
+		logger:                logger,
+		environment:           opts.Environment,
+		allowedBindMountPaths: opts.AllowedBindMountPaths,
+		secretsKey:            opts.SecretsKey,
+		credentials:           opts.Credentials,
+		redactor:              secrets.NewRedactor(),
+		resourcePrefix:        opts.ResourcePrefix,
+		repositoryBuildAppCreator: apps.NewRepositoryBuilderAppCreator(
+			logger,
+			dockerClient,
+			docker.Docker{Logger: logger},
+			"",
+			opts.ResourcePrefix,
+			// Repositories aren't downloaded when planning
+			tarball.Limits{},
+		),
+		dockefileAppCreator:  apps.NewDockefileAppCreator(logger, dockerClient),
+		appsConfigurationDir: defaultAppsConfigurationDir,
+		defaultSettings:      getDefaultServerSettings(false),
+	}
+
+	loaded, err := c.loadConfiguration(root)
+	if err != nil {
+		return RevisionPlan{}, err
+	}
+
+	plan, err := containermanager.ComputePlan(ctx, logger, dockerClient, opts.ResourcePrefix, loaded.apps, loaded.getInvalidAppNames(), loaded.pausedAppNames)
+	if err != nil {
+		return RevisionPlan{}, err
+	}
+
+	return RevisionPlan{Plan: plan, AppErrors: loaded.appErrors}, nil
+}
@@ -0,0 +1,84 @@
+package configuration
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/krystofrezac/lifebuoy/internal/sources"
+)
+
+// Configuration in a repository of Github, GitLab or Gitea
+type RepositorySource struct {
+	providerOpts       sources.ProviderOpts
+	provider           sources.Provider
+	repositoryOwner    string
+	repositoryName     string
+	repositoryRevision *string
+	downloadDir        string
+}
+
+func NewRepositorySource(
+	logger *slog.Logger,
+	providerOpts sources.ProviderOpts,
+	repositoryOwner string,
+	repositoryName string,
+	repositoryRevision *string,
+	managedStoragePath string,
+) (RepositorySource, error) {
+	const downloadDir = "configuration"
+
+	provider, err := sources.NewProvider(logger, providerOpts)
+	if err != nil {
+		return RepositorySource{}, err
+	}
+
+	return RepositorySource{
+		providerOpts:       providerOpts,
+		provider:           provider,
+		repositoryOwner:    repositoryOwner,
+		repositoryName:     repositoryName,
+		repositoryRevision: repositoryRevision,
+		downloadDir:        path.Join(managedStoragePath, downloadDir),
+	}, nil
+}
+
+// Revision is sha of the commit
+func (r RepositorySource) GetRevision(ctx context.Context) (string, error) {
+	return r.provider.GetSha(ctx, r.repositoryOwner, r.repositoryName, r.repositoryRevision)
+}
+
+// Pushes to other branches are matched too, the check finds out whether the revision changed
+func (r RepositorySource) IsGithubRepository(owner string, name string) bool {
+	return r.providerOpts.Type == sources.ProviderGithub &&
+		strings.EqualFold(r.repositoryOwner, owner) &&
+		strings.EqualFold(r.repositoryName, name)
+}
+
+func (r RepositorySource) GetPollInterval() time.Duration {
+	return r.provider.GetPollInterval()
+}
+
+func (r RepositorySource) Checkout(ctx context.Context, revision string) (string, error) {
+	// Files deleted from the repository would stay there otherwise
+	err := os.RemoveAll(r.downloadDir)
+	if err != nil {
+		return "", err
+	}
+
+	err = r.provider.DownloadRepository(
+		ctx,
+		r.repositoryOwner,
+		r.repositoryName,
+		revision,
+		r.downloadDir,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return r.downloadDir, nil
+}
@@ -0,0 +1,98 @@
+package configuration
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"time"
+
+	containermanager "github.com/krystofrezac/lifebuoy/internal/container_manager"
+	"gopkg.in/yaml.v3"
+)
+
+// Optional file in the configuration root
+const serverSettingsFile = "lifebuoy.yaml"
+
+const defaultPollInterval = 60 * time.Second
+
+// Webhooks trigger the checks, polling is only a fallback for missed deliveries
+const defaultPollIntervalWithWebhooks = 10 * time.Minute
+const defaultCheckTimeout = 10 * time.Second
+const defaultReconcileInterval = 10 * time.Second
+const defaultTraefikImage = "traefik:v3.1.0"
+
+// Settings of the server that live in the configuration repository, so they can be changed without a restart
+type serverSettings struct {
+	Version int `validate:"required,oneof=1"`
+	// How often the configuration source is checked for a new revision. Beware the rate limit of Github
+	PollInterval time.Duration `yaml:"pollInterval" validate:"omitempty,min=1s"`
+	// Maximum duration of a single configuration check
+	CheckTimeout time.Duration `yaml:"checkTimeout" validate:"omitempty,min=1s"`
+	// How often containers are compared with the configuration
+	ReconcileInterval time.Duration `yaml:"reconcileInterval" validate:"omitempty,min=1s"`
+	// Maximum number of images built at the same time
+	BuildConcurrency int `yaml:"buildConcurrency" validate:"omitempty,min=1"`
+	Traefik          struct {
+		// Base image of the built-in Traefik app
+		Image string `validate:"excluded_with=Dockerfile"`
+		// Replaces the whole Dockerfile of the built-in Traefik app. It has to configure the Docker provider and the `web` entrypoint
+		Dockerfile string `validate:"excluded_with=Image"`
+	}
+}
+
+// Returns settings with defaults filled in. Missing file is the same as an empty one
+func readServerSettings(root string, defaults serverSettings) (serverSettings, []ConfigurationError) {
+	settings := serverSettings{Version: 1}
+
+	content, err := os.ReadFile(path.Join(root, serverSettingsFile))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return serverSettings{}, []ConfigurationError{{File: serverSettingsFile, Message: err.Error()}}
+	}
+	if err == nil {
+		settings = serverSettings{}
+		err = yaml.Unmarshal(content, &settings)
+		if err != nil {
+			return serverSettings{}, []ConfigurationError{{File: serverSettingsFile, Message: fmt.Sprintf("Failed to decode: %s", err.Error())}}
+		}
+	}
+
+	configurationErrors := getValidationErrors(newValidator().Struct(settings))
+	if len(configurationErrors) > 0 {
+		for i := range configurationErrors {
+			configurationErrors[i].File = serverSettingsFile
+		}
+		return serverSettings{}, configurationErrors
+	}
+
+	return settings.withDefaults(defaults), nil
+}
+
+func getDefaultServerSettings(webhooksEnabled bool) serverSettings {
+	settings := serverSettings{
+		Version:           1,
+		PollInterval:      defaultPollInterval,
+		CheckTimeout:      defaultCheckTimeout,
+		ReconcileInterval: defaultReconcileInterval,
+		BuildConcurrency:  containermanager.DefaultBuildConcurrency,
+	}
+	settings.Traefik.Image = defaultTraefikImage
+
+	if webhooksEnabled {
+		settings.PollInterval = defaultPollIntervalWithWebhooks
+	}
+
+	return settings
+}
+
+func (s serverSettings) withDefaults(defaults serverSettings) serverSettings {
+	s.PollInterval = withDefault(s.PollInterval, defaults.PollInterval)
+	s.CheckTimeout = withDefault(s.CheckTimeout, defaults.CheckTimeout)
+	s.ReconcileInterval = withDefault(s.ReconcileInterval, defaults.ReconcileInterval)
+	s.BuildConcurrency = withDefault(s.BuildConcurrency, defaults.BuildConcurrency)
+	if s.Traefik.Dockerfile == "" {
+		s.Traefik.Image = withDefault(s.Traefik.Image, defaults.Traefik.Image)
+	}
+	return s
+}
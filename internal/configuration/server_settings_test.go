@@ -0,0 +1,48 @@
+package configuration
+
+import (
+	"path"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReadServerSettings_MissingFile(t *testing.T) {
+	settings, configurationErrors := readServerSettings(t.TempDir(), getDefaultServerSettings(false))
+	if len(configurationErrors) > 0 {
+		t.Fatalf("Expected no errors, got %+v", configurationErrors)
+	}
+	if settings != getDefaultServerSettings(false) {
+		t.Fatalf("Expected default settings, got %+v", settings)
+	}
+}
+
+func TestReadServerSettings(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, path.Join(root, "lifebuoy.yaml"), "version: 1\npollInterval: 5m\nbuildConcurrency: 3\ntraefik: {image: traefik:v3.2.0}\n")
+
+	settings, configurationErrors := readServerSettings(root, getDefaultServerSettings(true))
+	if len(configurationErrors) > 0 {
+		t.Fatalf("Expected no errors, got %+v", configurationErrors)
+	}
+
+	expected := getDefaultServerSettings(true)
+	expected.PollInterval = 5 * time.Minute
+	expected.BuildConcurrency = 3
+	expected.Traefik.Image = "traefik:v3.2.0"
+	if settings != expected {
+		t.Fatalf("Expected %+v, got %+v", expected, settings)
+	}
+}
+
+func TestReadServerSettings_Invalid(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, path.Join(root, "lifebuoy.yaml"), "version: 1\npollInterval: 10ms\n")
+
+	_, configurationErrors := readServerSettings(root, getDefaultServerSettings(false))
+
+	expected := []ConfigurationError{{File: "lifebuoy.yaml", Field: "pollInterval", Message: "Must be at least 1s"}}
+	if !reflect.DeepEqual(configurationErrors, expected) {
+		t.Fatalf("Expected %+v, got %+v", expected, configurationErrors)
+	}
+}
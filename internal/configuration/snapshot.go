@@ -0,0 +1,124 @@
+package configuration
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Last applied configuration revision, so the server can start without access to the configuration source
+const snapshotDir = "applied-configuration"
+
+// Inside of the snapshot directory
+const snapshotRevisionFile = "revision"
+const snapshotConfigurationDir = "configuration"
+
+// Replaced snapshot is moved here first, so there is always a snapshot to load, even after a crash in the middle of the replacement
+const previousSnapshotSuffix = ".old"
+
+// Copies the configuration and writes its revision. The previous snapshot is replaced only when the copy succeeds.
+// Secrets are copied still encrypted
+func saveSnapshot(managedStoragePath string, configPath string, revision string) error {
+	target := filepath.Join(managedStoragePath, snapshotDir)
+	temporary := target + ".new"
+
+	err := os.RemoveAll(temporary)
+	if err != nil {
+		return err
+	}
+
+	err = copyDir(configPath, filepath.Join(temporary, snapshotConfigurationDir))
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(filepath.Join(temporary, snapshotRevisionFile), []byte(revision), 0600)
+	if err != nil {
+		return err
+	}
+
+	previous := target + previousSnapshotSuffix
+	err = os.RemoveAll(previous)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(target, previous)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	err = os.Rename(temporary, target)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(previous)
+}
+
+// Returns path to the root of the configuration and its revision. Empty revision = there is no snapshot
+func loadSnapshot(managedStoragePath string) (string, string, error) {
+	target := filepath.Join(managedStoragePath, snapshotDir)
+
+	revision, err := os.ReadFile(filepath.Join(target, snapshotRevisionFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		// The replacement was interrupted
+		target += previousSnapshotSuffix
+		revision, err = os.ReadFile(filepath.Join(target, snapshotRevisionFile))
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", "", nil
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	return filepath.Join(target, snapshotConfigurationDir), strings.TrimSpace(string(revision)), nil
+}
+
+// Only directories and regular files are copied. `.git` directories are skipped
+func copyDir(source string, destination string) error {
+	return filepath.WalkDir(source, func(sourcePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relativePath, err := filepath.Rel(source, sourcePath)
+		if err != nil {
+			return err
+		}
+		destinationPath := filepath.Join(destination, relativePath)
+
+		if entry.IsDir() {
+			if entry.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return os.MkdirAll(destinationPath, 0700)
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		return copyFile(sourcePath, destinationPath)
+	})
+}
+
+func copyFile(source string, destination string) error {
+	sourceFile, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer sourceFile.Close()
+
+	destinationFile, err := os.OpenFile(destination, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(destinationFile, sourceFile)
+	if err != nil {
+		destinationFile.Close()
+		return err
+	}
+	return destinationFile.Close()
+}
@@ -0,0 +1,83 @@
+package configuration
+
+import (
+	"os"
+	"path"
+	"testing"
+)
+
+func TestSnapshot(t *testing.T) {
+	managedStoragePath := t.TempDir()
+	configPath := t.TempDir()
+	writeTestFile(t, path.Join(configPath, "apps", "app.yaml"), "version: 1\n")
+	writeTestFile(t, path.Join(configPath, ".git", "HEAD"), "ref: refs/heads/main\n")
+
+	_, revision, err := loadSnapshot(managedStoragePath)
+	if err != nil || revision != "" {
+		t.Fatalf("Expected no snapshot, got revision '%s' and error %v", revision, err)
+	}
+
+	err = saveSnapshot(managedStoragePath, configPath, "first")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, path.Join(configPath, "apps", "app.yaml"), "version: 2\n")
+	err = saveSnapshot(managedStoragePath, configPath, "second")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshotPath, revision, err := loadSnapshot(managedStoragePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revision != "second" {
+		t.Fatalf("Expected revision 'second', got '%s'", revision)
+	}
+
+	content, err := os.ReadFile(path.Join(snapshotPath, "apps", "app.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "version: 2\n" {
+		t.Fatalf("Expected content of the second revision, got '%s'", content)
+	}
+
+	if _, err := os.Stat(path.Join(snapshotPath, ".git")); !os.IsNotExist(err) {
+		t.Fatalf("Expected .git to be skipped, got %v", err)
+	}
+}
+
+func TestSnapshot_InterruptedReplacement(t *testing.T) {
+	managedStoragePath := t.TempDir()
+	configPath := t.TempDir()
+	writeTestFile(t, path.Join(configPath, "apps", "app.yaml"), "version: 1\n")
+
+	err := saveSnapshot(managedStoragePath, configPath, "first")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Crash after the snapshot was moved aside
+	target := path.Join(managedStoragePath, snapshotDir)
+	err = os.Rename(target, target+previousSnapshotSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, revision, err := loadSnapshot(managedStoragePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revision != "first" {
+		t.Fatalf("Expected revision 'first', got '%s'", revision)
+	}
+
+	err = saveSnapshot(managedStoragePath, configPath, "second")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, revision, err = loadSnapshot(managedStoragePath)
+	if err != nil || revision != "second" {
+		t.Fatalf("Expected revision 'second', got '%s' and error %v", revision, err)
+	}
+}
@@ -0,0 +1,25 @@
+package configuration
+
+import (
+	"context"
+	"time"
+)
+
+// Place where the configuration repository lives
+type Source interface {
+	// Identifier of the current revision, it has to change whenever the content changes
+	GetRevision(ctx context.Context) (string, error)
+	// Makes the revision available on disk and returns path to the root of the configuration
+	Checkout(ctx context.Context, revision string) (string, error)
+}
+
+// Implemented by sources that can tell whether a push to a Github repository changes the configuration.
+// Pushes to any repository trigger a check of other sources
+type githubRepositorySource interface {
+	IsGithubRepository(owner string, name string) bool
+}
+
+// Implemented by sources with a rate limit. Checks are postponed until the poll interval passed, which can be longer than the configured one
+type rateLimitedSource interface {
+	GetPollInterval() time.Duration
+}
@@ -0,0 +1,49 @@
+package configuration
+
+import (
+	"github.com/krystofrezac/lifebuoy/internal/secrets"
+)
+
+type ValidationOpts struct {
+	// Empty = no environment overlays
+	Environment           string
+	AllowedBindMountPaths []string
+	// nil = only the format of secrets is checked
+	SecretsKey *secrets.PrivateKey
+}
+
+// Checks the configuration the same way as the server does, but without access to Docker or the configuration source.
+// root is the root of the configuration repository
+func Validate(root string, opts ValidationOpts) []ConfigurationError {
+	settings, configurationErrors := readServerSettings(root, getDefaultServerSettings(false))
+	if len(configurationErrors) > 0 {
+		settings = getDefaultServerSettings(false)
+	}
+
+	files, filesErrors := readAppConfigurationFiles(root, defaultAppsConfigurationDir, opts.Environment, opts.AllowedBindMountPaths)
+	configurationErrors = append(configurationErrors, filesErrors...)
+
+	var names []string
+	for _, file := range files {
+		names = append(names, file.appName)
+
+		// The server doesn't decrypt secrets of paused apps
+		if opts.SecretsKey != nil && !file.configuration.isPaused() {
+			_, _, secretsErrors := decryptSecrets(file, opts.SecretsKey, secrets.NewRedactor())
+			configurationErrors = append(configurationErrors, secretsErrors...)
+		}
+	}
+
+	for _, app := range (&ConfigurationManager{}).getDefaultApps(settings) {
+		names = append(names, app.Configuration().AppName)
+	}
+	err := checkNameCollisions(names)
+	if err != nil {
+		configurationErrors = append(configurationErrors, ConfigurationError{
+			File:    defaultAppsConfigurationDir,
+			Message: err.Error(),
+		})
+	}
+
+	return configurationErrors
+}
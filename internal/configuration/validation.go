@@ -0,0 +1,204 @@
+package configuration
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/krystofrezac/lifebuoy/internal/secrets"
+)
+
+func newValidator() *validator.Validate {
+	validate := validator.New(validator.WithRequiredStructEnabled())
+
+	// Errors contain the same field names as the YAML documents
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "" {
+			return strings.ToLower(field.Name)
+		}
+		return name
+	})
+
+	// Registration fails only for empty tags or nil functions
+	_ = validate.RegisterValidation("relative_path", validateRelativePath)
+	_ = validate.RegisterValidation("route_url", validateRouteUrl)
+	_ = validate.RegisterValidation("volume_name", validateVolumeName)
+	_ = validate.RegisterValidation("absolute_path", validateAbsolutePath)
+	_ = validate.RegisterValidation("env_name", validateEnvName)
+	_ = validate.RegisterValidation("encrypted_secret", validateEncryptedSecret)
+
+	return validate
+}
+
+// Path has to be relative and must not point outside of its root
+func validateRelativePath(fl validator.FieldLevel) bool {
+	return filepath.IsLocal(fl.Field().String())
+}
+
+// Docker volumes are named `<prefix><app name>_<volume name>`. Without `_` in volume names, different apps and volumes can't end up with the same name
+var volumeNameRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9.-]*$`)
+
+func validateVolumeName(fl validator.FieldLevel) bool {
+	return volumeNameRegex.MatchString(fl.Field().String())
+}
+
+// Path has to be absolute and in its shortest form
+func validateAbsolutePath(fl validator.FieldLevel) bool {
+	value := fl.Field().String()
+	return filepath.IsAbs(value) && filepath.Clean(value) == value
+}
+
+var envNameRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+func validateEnvName(fl validator.FieldLevel) bool {
+	return envNameRegex.MatchString(fl.Field().String())
+}
+
+func validateEncryptedSecret(fl validator.FieldLevel) bool {
+	return secrets.IsEncryptedValue(fl.Field().String())
+}
+
+func validateRouteUrl(fl validator.FieldLevel) bool {
+	_, _, err := parseRouteUrl(fl.Field().String())
+	return err == nil
+}
+
+var hostRegex = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?(\.[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?)*$`)
+var pathPrefixRegex = regexp.MustCompile(`^(/[a-zA-Z0-9._~!$&'()*+,;=:@%-]+)*/?$`)
+
+// Splits url in format <host>[/<path prefix>] into host and path prefix. The path prefix always starts with `/` and doesn't end with `/` unless it's the root
+func parseRouteUrl(url string) (string, string, error) {
+	host, path, hasPath := strings.Cut(url, "/")
+	if !hostRegex.MatchString(host) {
+		return "", "", errors.New("Invalid host")
+	}
+
+	pathPrefix := "/" + path
+	if !hasPath || pathPrefix == "/" {
+		return host, "/", nil
+	}
+	if !pathPrefixRegex.MatchString(pathPrefix) {
+		return "", "", errors.New("Invalid path prefix")
+	}
+
+	return host, strings.TrimSuffix(pathPrefix, "/"), nil
+}
+
+// Managed volume names have to be unique within the app and manual volumes can only bind paths under one of allowedBindMountPaths
+func checkVolumes(configuration appConfiguration, allowedBindMountPaths []string) []ConfigurationError {
+	var configurationErrors []ConfigurationError
+	managedNames := map[string]struct{}{}
+	for i, volume := range configuration.Runtime.Volumes {
+		switch volume.Type {
+		case "managed":
+			if _, ok := managedNames[volume.Name]; ok {
+				configurationErrors = append(configurationErrors, ConfigurationError{
+					Field:   fmt.Sprintf("runtime.volumes[%d].name", i),
+					Message: fmt.Sprintf("Duplicate managed volume name `%s`", volume.Name),
+				})
+			}
+			managedNames[volume.Name] = struct{}{}
+
+		case "manual":
+			if !isBindMountAllowed(volume.From, allowedBindMountPaths) {
+				configurationErrors = append(configurationErrors, ConfigurationError{
+					Field:   fmt.Sprintf("runtime.volumes[%d].from", i),
+					Message: fmt.Sprintf("Host path `%s` is not allowed to be mounted, allowed paths: %v", volume.From, allowedBindMountPaths),
+				})
+			}
+		}
+	}
+
+	return configurationErrors
+}
+
+// Symlinks are resolved for existing paths, so they can't be used to escape the allowed paths
+func isBindMountAllowed(hostPath string, allowedBindMountPaths []string) bool {
+	paths := []string{filepath.Clean(hostPath)}
+	resolvedPath, err := filepath.EvalSymlinks(hostPath)
+	if err == nil {
+		paths = append(paths, resolvedPath)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return false
+	}
+
+	for _, path := range paths {
+		if !isPathUnderAny(path, allowedBindMountPaths) {
+			return false
+		}
+	}
+	return true
+}
+
+func isPathUnderAny(path string, parents []string) bool {
+	for _, parent := range parents {
+		if !filepath.IsAbs(parent) {
+			continue
+		}
+
+		relative, err := filepath.Rel(filepath.Clean(parent), path)
+		if err == nil && filepath.IsLocal(relative) {
+			return true
+		}
+	}
+	return false
+}
+
+// Exactly one provider has to be set
+func checkSource(configuration appConfiguration) []ConfigurationError {
+	source := configuration.Source
+	providers := 0
+	for _, provider := range []*repositorySource{source.Github, source.Gitlab, source.Gitea} {
+		if provider != nil {
+			providers++
+		}
+	}
+	if providers != 1 {
+		return []ConfigurationError{{Field: "source", Message: "Exactly one of `github`, `gitlab` and `gitea` has to be set"}}
+	}
+
+	if source.Gitea != nil && source.Gitea.BaseUrl == "" {
+		return []ConfigurationError{{Field: "source.gitea.baseUrl", Message: "Is required"}}
+	}
+	return nil
+}
+
+// Every environment variable and file can be set only once
+func checkSecrets(configuration appConfiguration) []ConfigurationError {
+	var configurationErrors []ConfigurationError
+	envNames := map[string]struct{}{}
+	for name := range configuration.Runtime.Env {
+		envNames[name] = struct{}{}
+	}
+	files := map[string]struct{}{}
+
+	for i, secret := range configuration.Runtime.Secrets {
+		if secret.Env != "" {
+			if _, ok := envNames[secret.Env]; ok {
+				configurationErrors = append(configurationErrors, ConfigurationError{
+					Field:   fmt.Sprintf("runtime.secrets[%d].env", i),
+					Message: fmt.Sprintf("Environment variable `%s` is set multiple times", secret.Env),
+				})
+			}
+			envNames[secret.Env] = struct{}{}
+		}
+
+		if secret.File != "" {
+			if _, ok := files[secret.File]; ok {
+				configurationErrors = append(configurationErrors, ConfigurationError{
+					Field:   fmt.Sprintf("runtime.secrets[%d].file", i),
+					Message: fmt.Sprintf("Secret file `%s` is set multiple times", secret.File),
+				})
+			}
+			files[secret.File] = struct{}{}
+		}
+	}
+
+	return configurationErrors
+}
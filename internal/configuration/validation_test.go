@@ -0,0 +1,104 @@
+package configuration
+
+import (
+	"path"
+	"reflect"
+	"testing"
+)
+
+func TestIsBindMountAllowed(t *testing.T) {
+	allowed := []string{"/srv/shared", "/mnt/data/"}
+
+	tests := []struct {
+		hostPath string
+		expected bool
+	}{
+		{"/srv/shared", true},
+		{"/srv/shared/app", true},
+		{"/mnt/data/app/nested", true},
+		{"/srv/shared/../../etc", false},
+		{"/srv/shared-other", false},
+		{"/srv", false},
+		{"/", false},
+		{"/var/run/docker.sock", false},
+	}
+
+	for _, test := range tests {
+		if res := isBindMountAllowed(test.hostPath, allowed); res != test.expected {
+			t.Errorf("isBindMountAllowed(%q) = %v, expected %v", test.hostPath, res, test.expected)
+		}
+	}
+}
+
+func TestIsBindMountAllowed_NothingAllowed(t *testing.T) {
+	if isBindMountAllowed("/srv/shared", nil) {
+		t.Fatal("Expected false")
+	}
+}
+
+func TestValidate_ReportsErrorsOfAllFiles(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, path.Join(root, "apps", "first.yaml"), "version: 2\nsource:\n  github: {owner: a, repository: b, revision: c}\n")
+	writeTestFile(t, path.Join(root, "apps", "second.yaml"), "version: 1\nsource:\n  github: {owner: a, repository: b}\n")
+	writeTestFile(t, path.Join(root, "apps", "valid.yaml"), "version: 1\nsource:\n  github: {owner: a, repository: b, revision: c}\n")
+
+	configurationErrors := Validate(root, ValidationOpts{})
+
+	expected := []ConfigurationError{
+		{File: "apps/first.yaml", AppName: "first", Field: "version", Message: "Must be one of: 1"},
+		{File: "apps/second.yaml", AppName: "second", Field: "source.github.revision", Message: "Is required"},
+	}
+	if !reflect.DeepEqual(configurationErrors, expected) {
+		t.Fatalf("Expected %+v, got %+v", expected, configurationErrors)
+	}
+}
+
+func TestValidate_Source(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, path.Join(root, "apps", "none.yaml"), "version: 1\n")
+	writeTestFile(t, path.Join(root, "apps", "both.yaml"), "version: 1\nsource:\n  github: {owner: a, repository: b, revision: c}\n  gitlab: {owner: a, repository: b, revision: c}\n")
+	writeTestFile(t, path.Join(root, "apps", "gitea.yaml"), "version: 1\nsource:\n  gitea: {owner: a, repository: b, revision: c}\n")
+	writeTestFile(t, path.Join(root, "apps", "gitlab.yaml"), "version: 1\nsource:\n  gitlab: {baseUrl: 'https://gitlab.example.com', owner: group/subgroup, repository: b, revision: c}\n")
+
+	configurationErrors := Validate(root, ValidationOpts{})
+
+	expected := []ConfigurationError{
+		{File: "apps/both.yaml", AppName: "both", Field: "source", Message: "Exactly one of `github`, `gitlab` and `gitea` has to be set"},
+		{File: "apps/gitea.yaml", AppName: "gitea", Field: "source.gitea.baseUrl", Message: "Is required"},
+		{File: "apps/none.yaml", AppName: "none", Field: "source", Message: "Exactly one of `github`, `gitlab` and `gitea` has to be set"},
+	}
+	if !reflect.DeepEqual(configurationErrors, expected) {
+		t.Fatalf("Expected %+v, got %+v", expected, configurationErrors)
+	}
+}
+
+func TestValidate_UnknownFields(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, path.Join(root, "apps", "typo.yaml"), "version: 1\nsource:\n  github: {owner: a, repository: b, revison: c}\nruntim: {}\n")
+
+	configurationErrors := Validate(root, ValidationOpts{})
+
+	expected := []ConfigurationError{
+		{File: "apps/typo.yaml", AppName: "typo", Message: "Failed to decode: line 3: unknown field `revison`"},
+		{File: "apps/typo.yaml", AppName: "typo", Message: "Failed to decode: line 4: unknown field `runtim`"},
+	}
+	if !reflect.DeepEqual(configurationErrors, expected) {
+		t.Fatalf("Expected %+v, got %+v", expected, configurationErrors)
+	}
+}
+
+func TestValidate_VolumeNamesDontCollide(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, path.Join(root, "apps", "my.yaml"), "version: 1\nsource:\n  github: {owner: a, repository: b, revision: c}\nruntime:\n  volumes: [{type: managed, name: app_data, to: /data}]\n")
+	writeTestFile(t, path.Join(root, "apps", "my_app.yaml"), "version: 1\nsource:\n  github: {owner: a, repository: b, revision: c}\nruntime:\n  volumes: [{type: managed, name: data, to: /data}]\n")
+
+	configurationErrors := Validate(root, ValidationOpts{})
+
+	// Both would be `<prefix>my_app_data`
+	expected := []ConfigurationError{
+		{File: "apps/my.yaml", AppName: "my", Field: "runtime.volumes[0].name", Message: "Can contain only letters, numbers, `.` and `-`"},
+	}
+	if !reflect.DeepEqual(configurationErrors, expected) {
+		t.Fatalf("Expected %+v, got %+v", expected, configurationErrors)
+	}
+}
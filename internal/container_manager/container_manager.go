@@ -6,37 +6,72 @@ import (
 	"github.com/krystofrezac/lifebuoy/internal/apps"
 	"github.com/krystofrezac/lifebuoy/internal/queues"
 	"log/slog"
+	"slices"
 	"time"
 )
 
 var tickInterval = 10 * time.Second
 
+// Used until the settings are received
+const DefaultBuildConcurrency = 1
+
 const managedLabel = "dev.lifebuoy.managed"
 const appNameLabel = "dev.lifebuoy.app-name"
 
+// Exact resource prefix of the instance that created the resource
+const resourcePrefixLabel = "dev.lifebuoy.resource-prefix"
+const configurationHashLabel = "dev.lifebuoy.configuration-hash"
+
+// Number of the container among the replicas of its app, starting at 1
+const replicaLabel = "dev.lifebuoy.replica"
+
+type appsUpdate struct {
+	apps      []apps.App
+	changeset apps.Changeset
+	// Apps without a valid definition. Their containers are kept
+	invalidAppNames []string
+	// Containers of paused apps are stopped, but kept
+	pausedAppNames []string
+}
+
+type Settings struct {
+	// How often containers are compared with the configuration
+	ReconcileInterval time.Duration
+	// Maximum number of images built at the same time
+	BuildConcurrency int
+}
+
 type ContainerManager struct {
 	logger                    *slog.Logger
 	dockerClient              *client.Client
 	resourcePrefix            string
-	appsChangeChannel         chan []apps.App
+	appsChangeChannel         chan appsUpdate
+	settingsChangeChannel     chan Settings
+	reconcileAppsChannel      chan []string
 	reconcileFinishChannel    chan struct{}
 	ticker                    *time.Ticker
 	apps                      []apps.App
+	invalidAppNames           []string
+	pausedAppNames            []string
 	receivedAppsConfiguration bool
 	buildProcessor            *queues.UniqueJobProcessor
 }
 
 func NewContainerManager(logger *slog.Logger, dockerClient *client.Client, resourcePrefix string) ContainerManager {
-	appsChangeChannel := make(chan []apps.App)
+	appsChangeChannel := make(chan appsUpdate)
+	settingsChangeChannel := make(chan Settings)
+	reconcileAppsChannel := make(chan []string)
 	reconcileFinishChannel := make(chan struct{})
 	ticker := time.NewTicker(tickInterval)
-	buildProcessor := queues.NewUniqueJobProcessor(1)
+	buildProcessor := queues.NewUniqueJobProcessor(DefaultBuildConcurrency)
 
 	return ContainerManager{
 		logger:                    logger,
 		dockerClient:              dockerClient,
 		resourcePrefix:            resourcePrefix,
 		appsChangeChannel:         appsChangeChannel,
+		settingsChangeChannel:     settingsChangeChannel,
+		reconcileAppsChannel:      reconcileAppsChannel,
 		reconcileFinishChannel:    reconcileFinishChannel,
 		ticker:                    ticker,
 		apps:                      nil,
@@ -51,14 +86,27 @@ func (c ContainerManager) Start(ctx context.Context) {
 	reconcileIsRunning := false
 
 	for {
+		// nil = reconcile all apps
+		var reconciledAppNames []string
+
 		select {
-		case newApps := <-c.appsChangeChannel:
-			c.apps = newApps
+		case update := <-c.appsChangeChannel:
+			c.apps = update.apps
+			c.invalidAppNames = update.invalidAppNames
 			c.receivedAppsConfiguration = true
+			reconciledAppNames = append(update.changeset.AffectedApps(), getChangedAppNames(c.pausedAppNames, update.pausedAppNames)...)
+			c.pausedAppNames = update.pausedAppNames
+		case settings := <-c.settingsChangeChannel:
+			c.ticker.Reset(settings.ReconcileInterval)
+			c.buildProcessor.SetProcessorPoolSize(settings.BuildConcurrency)
+			continue
+		case appNames := <-c.reconcileAppsChannel:
+			reconciledAppNames = appNames
 		case <-c.ticker.C:
 		case event := <-c.buildProcessor.JobFinishedChannel:
 			// TODO: retry
 			if event.Result != nil {
+				c.logger.Error("App build failed", "appName", event.Id, "err", event.Result)
 				continue
 			}
 		case <-c.reconcileFinishChannel:
@@ -77,10 +125,88 @@ func (c ContainerManager) Start(ctx context.Context) {
 		}
 
 		reconcileIsRunning = true
-		go runReconcile(ctx, c.logger, c.dockerClient, c.buildProcessor, c.reconcileFinishChannel, c.resourcePrefix, c.apps)
+		go runReconcile(
+			ctx,
+			c.logger,
+			c.dockerClient,
+			c.buildProcessor,
+			c.reconcileFinishChannel,
+			c.resourcePrefix,
+			c.apps,
+			reconciledAppNames,
+			c.invalidAppNames,
+			c.pausedAppNames,
+		)
+	}
+}
+
+// changeset describes the difference from the previously sent apps. Only the affected apps are reconciled right away, the rest waits for the next tick.
+// Containers of apps that are neither in apps nor in invalidAppNames are removed. Apps whose paused state changed are reconciled right away too
+func (c ContainerManager) UpdateApps(apps []apps.App, changeset apps.Changeset, invalidAppNames []string, pausedAppNames []string) {
+	c.appsChangeChannel <- appsUpdate{apps: apps, changeset: changeset, invalidAppNames: invalidAppNames, pausedAppNames: pausedAppNames}
+}
+
+// Read only, can be called from any goroutine
+func (c ContainerManager) Plan(ctx context.Context, appsToPlan []apps.App, invalidAppNames []string, pausedAppNames []string) (Plan, error) {
+	return ComputePlan(ctx, c.logger, c.dockerClient, c.resourcePrefix, appsToPlan, invalidAppNames, pausedAppNames)
+}
+
+func getKeptAppNames(configuredApps []apps.App, invalidAppNames []string) []string {
+	res := slices.Clone(invalidAppNames)
+	for _, app := range configuredApps {
+		res = append(res, app.Configuration().AppName)
+	}
+	return res
+}
+
+// Reconciles the apps right away, e.g. because their branch moved
+func (c ContainerManager) ReconcileApps(appNames []string) {
+	c.reconcileAppsChannel <- appNames
+}
+
+// Takes effect without restart
+func (c ContainerManager) UpdateSettings(settings Settings) {
+	c.settingsChangeChannel <- settings
+}
+
+// Names that are only in one of the lists
+func getChangedAppNames(oldAppNames []string, newAppNames []string) []string {
+	var res []string
+	for _, appName := range oldAppNames {
+		if !slices.Contains(newAppNames, appName) {
+			res = append(res, appName)
+		}
+	}
+	for _, appName := range newAppNames {
+		if !slices.Contains(oldAppNames, appName) {
+			res = append(res, appName)
+		}
 	}
+	return res
 }
 
-func (c ContainerManager) UpdateApps(apps []apps.App) {
-	c.appsChangeChannel <- apps
+// Paused apps aren't created nor started
+func getUnpausedApps(allApps []apps.App, pausedAppNames []string) []apps.App {
+	var res []apps.App
+	for _, app := range allApps {
+		if !slices.Contains(pausedAppNames, app.Configuration().AppName) {
+			res = append(res, app)
+		}
+	}
+	return res
+}
+
+// appNames nil = all apps
+func filterApps(allApps []apps.App, appNames []string) []apps.App {
+	if appNames == nil {
+		return allApps
+	}
+
+	var res []apps.App
+	for _, app := range allApps {
+		if slices.Contains(appNames, app.Configuration().AppName) {
+			res = append(res, app)
+		}
+	}
+	return res
 }
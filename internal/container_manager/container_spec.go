@@ -0,0 +1,242 @@
+package containermanager
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/go-connections/nat"
+	"github.com/krystofrezac/lifebuoy/internal/apps"
+	"github.com/krystofrezac/lifebuoy/internal/secrets"
+)
+
+type containerSpec struct {
+	config     *container.Config
+	hostConfig *container.HostConfig
+	// Copied into the container after it's created. Key is the path inside of the container
+	secretFiles map[string]secrets.Value
+	// Changes whenever anything in the spec changes, so the container gets re-created
+	hash string
+}
+
+func (r reconcile) getContainerSpec(configuration apps.AppConfiguration, replica int) (containerSpec, error) {
+	exposedPorts, portBindings, err := nat.ParsePortSpecs(configuration.PortMappings)
+	if err != nil {
+		return containerSpec{}, fmt.Errorf("Failed to parse port mappings: %w", err)
+	}
+
+	labels := getTraefikLabels(r.resourcePrefix, configuration.AppName, configuration.Routes)
+	labels[managedLabel] = "true"
+	labels[resourcePrefixLabel] = r.resourcePrefix
+	labels[appNameLabel] = configuration.AppName
+	labels[replicaLabel] = strconv.Itoa(replica)
+
+	config := &container.Config{
+		Image:        configuration.Image,
+		Labels:       labels,
+		ExposedPorts: exposedPorts,
+		Env:          getEnv(configuration.Env, configuration.SecretEnv),
+		Healthcheck:  getHealthConfig(configuration.Healthcheck),
+	}
+	hostConfig := &container.HostConfig{
+		Binds:        configuration.Binds,
+		PortBindings: portBindings,
+		Mounts:       r.getMounts(configuration),
+	}
+
+	revealedSecretFiles := make(map[string]string, len(configuration.SecretFiles))
+	for path, value := range configuration.SecretFiles {
+		revealedSecretFiles[path] = value.Reveal()
+	}
+
+	// Maps are serialized with sorted keys, so the hash is stable
+	serialized, err := json.Marshal([]any{config, hostConfig, revealedSecretFiles})
+	if err != nil {
+		return containerSpec{}, err
+	}
+	hash := fmt.Sprintf("%x", sha256.Sum256(serialized))
+	labels[configurationHashLabel] = hash
+
+	return containerSpec{
+		config:      config,
+		hostConfig:  hostConfig,
+		secretFiles: configuration.SecretFiles,
+		hash:        hash,
+	}, nil
+}
+
+// Zero values are defaulted by Docker
+func getHealthConfig(healthcheck *apps.Healthcheck) *container.HealthConfig {
+	if healthcheck == nil {
+		return nil
+	}
+
+	return &container.HealthConfig{
+		Test:        []string{"CMD-SHELL", healthcheck.Command},
+		Interval:    healthcheck.Interval,
+		Timeout:     healthcheck.Timeout,
+		Retries:     healthcheck.Retries,
+		StartPeriod: healthcheck.StartPeriod,
+	}
+}
+
+func (r reconcile) getMounts(configuration apps.AppConfiguration) []mount.Mount {
+	mounts := make([]mount.Mount, 0, len(configuration.Volumes))
+	for _, appVolume := range configuration.Volumes {
+		switch appVolume.Type {
+		case apps.VolumeTypeManaged:
+			mounts = append(mounts, mount.Mount{
+				Type:     mount.TypeVolume,
+				Source:   r.getVolumeName(configuration.AppName, appVolume.Name),
+				Target:   appVolume.To,
+				ReadOnly: appVolume.ReadOnly,
+			})
+		case apps.VolumeTypeManual:
+			mounts = append(mounts, mount.Mount{
+				Type:     mount.TypeBind,
+				Source:   appVolume.From,
+				Target:   appVolume.To,
+				ReadOnly: appVolume.ReadOnly,
+			})
+		}
+	}
+	return mounts
+}
+
+// In Docker format <name>=<value>, sorted by name
+func getEnv(env map[string]string, secretEnv map[string]secrets.Value) []string {
+	res := make([]string, 0, len(env)+len(secretEnv))
+	for name, value := range env {
+		res = append(res, name+"="+value)
+	}
+	for name, value := range secretEnv {
+		res = append(res, name+"="+value.Reveal())
+	}
+	slices.Sort(res)
+	return res
+}
+
+// Numeric owner of files copied into a container
+type fileOwner struct {
+	uid int
+	gid int
+}
+
+// user is in Docker format `<user>[:<group>]`, both can be a name or a number. Names are looked up in passwd and group, contents of the files from the container
+func getFileOwner(user string, passwd string, group string) (fileOwner, error) {
+	if user == "" {
+		return fileOwner{}, nil
+	}
+
+	userName, groupName, hasGroup := strings.Cut(user, ":")
+	var owner fileOwner
+	if uid, err := strconv.Atoi(userName); err == nil {
+		owner.uid = uid
+		// Primary group of the user, if it has an entry
+		if entry := findEntry(passwd, 2, userName); entry != nil {
+			owner.gid, _ = strconv.Atoi(entry[3])
+		}
+	} else {
+		entry := findEntry(passwd, 0, userName)
+		if entry == nil {
+			return fileOwner{}, fmt.Errorf("User `%s` doesn't exist in the container", userName)
+		}
+		owner.uid, _ = strconv.Atoi(entry[2])
+		owner.gid, _ = strconv.Atoi(entry[3])
+	}
+
+	if !hasGroup {
+		return owner, nil
+	}
+	if gid, err := strconv.Atoi(groupName); err == nil {
+		owner.gid = gid
+		return owner, nil
+	}
+	entry := findEntry(group, 0, groupName)
+	if entry == nil {
+		return fileOwner{}, fmt.Errorf("Group `%s` doesn't exist in the container", groupName)
+	}
+	owner.gid, _ = strconv.Atoi(entry[2])
+	return owner, nil
+}
+
+// Line of /etc/passwd or /etc/group whose field has the value, split to fields. nil = not found
+func findEntry(content string, field int, value string) []string {
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Split(line, ":")
+		if len(fields) >= 4 && fields[field] == value {
+			return fields
+		}
+	}
+	return nil
+}
+
+// Tar archive with the files relative to the container root, including their parent directories.
+// Files are owned by owner, so apps running as a non-root user can read them
+func getSecretFilesArchive(secretFiles map[string]secrets.Value, owner fileOwner) (*bytes.Buffer, error) {
+	var buf bytes.Buffer
+	archive := tar.NewWriter(&buf)
+
+	paths := make([]string, 0, len(secretFiles))
+	for filePath := range secretFiles {
+		paths = append(paths, filePath)
+	}
+	slices.Sort(paths)
+
+	writtenDirs := map[string]struct{}{}
+	for _, filePath := range paths {
+		relativePath := strings.TrimPrefix(filePath, "/")
+
+		var dirs []string
+		for dir := path.Dir(relativePath); dir != "."; dir = path.Dir(dir) {
+			dirs = append(dirs, dir)
+		}
+		slices.Reverse(dirs)
+		for _, dir := range dirs {
+			if _, ok := writtenDirs[dir]; ok {
+				continue
+			}
+			writtenDirs[dir] = struct{}{}
+
+			err := archive.WriteHeader(&tar.Header{
+				Typeflag: tar.TypeDir,
+				Name:     dir + "/",
+				Mode:     0755,
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		content := []byte(secretFiles[filePath].Reveal())
+		err := archive.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     relativePath,
+			Size:     int64(len(content)),
+			Mode:     0400,
+			Uid:      owner.uid,
+			Gid:      owner.gid,
+		})
+		if err != nil {
+			return nil, err
+		}
+		_, err = archive.Write(content)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := archive.Close()
+	if err != nil {
+		return nil, err
+	}
+	return &buf, nil
+}
@@ -0,0 +1,119 @@
+package containermanager
+
+import (
+	"archive/tar"
+	"io"
+	"testing"
+
+	"github.com/krystofrezac/lifebuoy/internal/apps"
+	"github.com/krystofrezac/lifebuoy/internal/secrets"
+)
+
+func TestGetContainerSpec_HashIsStable(t *testing.T) {
+	r := reconcile{resourcePrefix: "dev.lifebuoy."}
+	configuration := apps.AppConfiguration{
+		AppName: "app",
+		Image:   "dev.lifebuoy.app:main",
+		Env:     map[string]string{"A": "1", "B": "2", "C": "3"},
+	}
+
+	first, err := r.getContainerSpec(configuration, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := r.getContainerSpec(configuration, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first.hash != second.hash {
+		t.Fatalf("Expected the same hash, got %s and %s", first.hash, second.hash)
+	}
+}
+
+func TestGetContainerSpec_EnvChangeChangesHash(t *testing.T) {
+	r := reconcile{resourcePrefix: "dev.lifebuoy."}
+	configuration := apps.AppConfiguration{
+		AppName: "app",
+		Image:   "dev.lifebuoy.app:main",
+		Env:     map[string]string{"A": "1"},
+	}
+
+	before, err := r.getContainerSpec(configuration, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	configuration.Env = map[string]string{"A": "2"}
+	after, err := r.getContainerSpec(configuration, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if before.hash == after.hash {
+		t.Fatal("Expected different hashes")
+	}
+	if after.config.Labels[configurationHashLabel] != after.hash {
+		t.Fatal("Expected the hash to be in the labels")
+	}
+}
+
+func TestGetFileOwner(t *testing.T) {
+	passwd := "root:x:0:0:root:/root:/bin/sh\napp:x:1000:1001::/home/app:/bin/sh\n"
+	group := "root:x:0:\napp:x:1001:\nstaff:x:50:\n"
+
+	tests := []struct {
+		user     string
+		expected fileOwner
+	}{
+		{"", fileOwner{uid: 0, gid: 0}},
+		{"app", fileOwner{uid: 1000, gid: 1001}},
+		{"1000", fileOwner{uid: 1000, gid: 1001}},
+		{"2000", fileOwner{uid: 2000, gid: 0}},
+		{"app:staff", fileOwner{uid: 1000, gid: 50}},
+		{"2000:3000", fileOwner{uid: 2000, gid: 3000}},
+	}
+	for _, test := range tests {
+		owner, err := getFileOwner(test.user, passwd, group)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if owner != test.expected {
+			t.Fatalf("Expected %+v for `%s`, got %+v", test.expected, test.user, owner)
+		}
+	}
+
+	_, err := getFileOwner("unknown", passwd, group)
+	if err == nil {
+		t.Fatal("Expected error for unknown user")
+	}
+}
+
+func TestGetSecretFilesArchive_FilesAreOwnedByUser(t *testing.T) {
+	archive, err := getSecretFilesArchive(
+		map[string]secrets.Value{"/run/secrets/key": secrets.NewValue("secret")},
+		fileOwner{uid: 1000, gid: 1001},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader := tar.NewReader(archive)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		if header.Name != "run/secrets/key" || header.Uid != 1000 || header.Gid != 1001 || header.Mode != 0400 {
+			t.Fatalf("Expected `run/secrets/key` owned by 1000:1001 with mode 0400, got `%s` %d:%d %o", header.Name, header.Uid, header.Gid, header.Mode)
+		}
+		return
+	}
+	t.Fatal("Archive doesn't contain the secret file")
+}
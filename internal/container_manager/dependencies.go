@@ -0,0 +1,38 @@
+package containermanager
+
+import (
+	"github.com/krystofrezac/lifebuoy/internal/apps"
+)
+
+// Dependencies go before the apps that depend on them, otherwise the order is kept.
+// Dependencies that aren't in the list are ignored. Cycles are rejected by the validation, here they are only broken
+func sortByDependencies(unsorted []apps.App) []apps.App {
+	appsByName := make(map[string]apps.App, len(unsorted))
+	for _, app := range unsorted {
+		appsByName[app.Configuration().AppName] = app
+	}
+
+	sorted := make([]apps.App, 0, len(unsorted))
+	visited := make(map[string]bool, len(unsorted))
+
+	var visit func(app apps.App)
+	visit = func(app apps.App) {
+		appName := app.Configuration().AppName
+		if visited[appName] {
+			return
+		}
+		visited[appName] = true
+
+		for _, dependency := range app.Configuration().DependsOn {
+			if dependencyApp, ok := appsByName[dependency]; ok {
+				visit(dependencyApp)
+			}
+		}
+		sorted = append(sorted, app)
+	}
+
+	for _, app := range unsorted {
+		visit(app)
+	}
+	return sorted
+}
@@ -0,0 +1,71 @@
+package containermanager
+
+import (
+	"context"
+	"testing"
+
+	"github.com/krystofrezac/lifebuoy/internal/apps"
+)
+
+type testApp struct {
+	configuration apps.AppConfiguration
+}
+
+func (a testApp) IsBuilt(context.Context) bool              { return true }
+func (a testApp) Build(context.Context) error               { return nil }
+func (a testApp) Configuration() apps.AppConfiguration      { return a.configuration }
+func (a testApp) Resolve(context.Context) (apps.App, error) { return a, nil }
+func (a testApp) Definition() any                           { return a.configuration }
+
+func newTestApp(appName string, dependsOn ...string) apps.App {
+	return testApp{configuration: apps.AppConfiguration{AppName: appName, DependsOn: dependsOn}}
+}
+
+func getAppNames(appsToName []apps.App) []string {
+	names := make([]string, 0, len(appsToName))
+	for _, app := range appsToName {
+		names = append(names, app.Configuration().AppName)
+	}
+	return names
+}
+
+func TestSortByDependencies(t *testing.T) {
+	tests := []struct {
+		name     string
+		apps     []apps.App
+		expected []string
+	}{
+		{
+			name:     "no dependencies",
+			apps:     []apps.App{newTestApp("b"), newTestApp("a")},
+			expected: []string{"b", "a"},
+		},
+		{
+			name:     "dependencies go first",
+			apps:     []apps.App{newTestApp("web", "api"), newTestApp("api", "db"), newTestApp("db")},
+			expected: []string{"db", "api", "web"},
+		},
+		{
+			name:     "missing dependency",
+			apps:     []apps.App{newTestApp("web", "paused"), newTestApp("db")},
+			expected: []string{"web", "db"},
+		},
+		{
+			name:     "cycle",
+			apps:     []apps.App{newTestApp("a", "b"), newTestApp("b", "a")},
+			expected: []string{"b", "a"},
+		},
+	}
+
+	for _, test := range tests {
+		res := getAppNames(sortByDependencies(test.apps))
+		if len(res) != len(test.expected) {
+			t.Fatalf("%s: expected %v, got %v", test.name, test.expected, res)
+		}
+		for i := range res {
+			if res[i] != test.expected[i] {
+				t.Fatalf("%s: expected %v, got %v", test.name, test.expected, res)
+			}
+		}
+	}
+}
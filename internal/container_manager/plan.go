@@ -0,0 +1,231 @@
+package containermanager
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+	"github.com/krystofrezac/lifebuoy/internal/apps"
+)
+
+type PlanActionType string
+
+const (
+	PlanActionBuild PlanActionType = "build"
+	// Created containers are started right away
+	PlanActionCreate PlanActionType = "create"
+	// Current container of the replica is removed and a new one is created
+	PlanActionRecreate PlanActionType = "recreate"
+	PlanActionStart    PlanActionType = "start"
+	// Container of a paused app is stopped, but kept
+	PlanActionStop   PlanActionType = "stop"
+	PlanActionRemove PlanActionType = "remove"
+)
+
+type PlanAction struct {
+	Type    PlanActionType `json:"type"`
+	AppName string         `json:"appName"`
+	// Image for builds, container name for the rest
+	Target string `json:"target"`
+}
+
+// App that can't be planned, e.g. because its revision can't be resolved
+type PlanProblem struct {
+	AppName string `json:"appName"`
+	Message string `json:"message"`
+}
+
+// What reconcile would change
+type Plan struct {
+	Actions  []PlanAction  `json:"actions"`
+	Problems []PlanProblem `json:"problems"`
+}
+
+func (p Plan) String() string {
+	if len(p.Actions) == 0 && len(p.Problems) == 0 {
+		return "No changes\n"
+	}
+
+	var builder strings.Builder
+	for _, action := range p.Actions {
+		fmt.Fprintf(&builder, "%-9s %-24s %s\n", action.Type, action.AppName, action.Target)
+	}
+	for _, problem := range p.Problems {
+		fmt.Fprintf(&builder, "%-9s %-24s %s\n", "problem", problem.AppName, problem.Message)
+	}
+	return builder.String()
+}
+
+// Compares the apps with the state of Docker the same way reconcile does, but doesn't change anything.
+// Containers of apps that are neither in appsToPlan nor in invalidAppNames are planned for removal, running containers of paused apps for stop
+func ComputePlan(
+	ctx context.Context,
+	logger *slog.Logger,
+	dockerClient *client.Client,
+	resourcePrefix string,
+	appsToPlan []apps.App,
+	invalidAppNames []string,
+	pausedAppNames []string,
+) (Plan, error) {
+	r := reconcile{
+		ctx:            ctx,
+		logger:         logger,
+		dockerClient:   dockerClient,
+		resourcePrefix: resourcePrefix,
+		apps:           sortByDependencies(getUnpausedApps(appsToPlan, pausedAppNames)),
+		allApps:        appsToPlan,
+		keptAppNames:   getKeptAppNames(appsToPlan, invalidAppNames),
+		pausedAppNames: pausedAppNames,
+	}
+
+	plan := Plan{}
+	for _, app := range r.apps {
+		appName := app.Configuration().AppName
+
+		resolved, err := app.Resolve(ctx)
+		if err != nil {
+			plan.Problems = append(plan.Problems, PlanProblem{AppName: appName, Message: err.Error()})
+			continue
+		}
+
+		actions, err := r.planApp(ctx, resolved)
+		if err != nil {
+			plan.Problems = append(plan.Problems, PlanProblem{AppName: appName, Message: err.Error()})
+			continue
+		}
+		plan.Actions = append(plan.Actions, actions...)
+
+		if !startsApp(actions) {
+			continue
+		}
+		dependency, err := r.getUnreadyDependency(ctx, resolved.Configuration())
+		if err != nil {
+			plan.Problems = append(plan.Problems, PlanProblem{AppName: appName, Message: err.Error()})
+			continue
+		}
+		if dependency != "" {
+			plan.Problems = append(plan.Problems, PlanProblem{
+				AppName: appName,
+				Message: fmt.Sprintf("Waiting for dependency `%s`, the app won't be started yet", dependency),
+			})
+		}
+	}
+
+	pausedContainers, err := r.getRunningContainersOfPausedApps(ctx)
+	if err != nil {
+		return Plan{}, err
+	}
+	for _, pausedContainer := range pausedContainers {
+		plan.Actions = append(plan.Actions, PlanAction{
+			Type:    PlanActionStop,
+			AppName: pausedContainer.Labels[appNameLabel],
+			Target:  strings.TrimPrefix(pausedContainer.Names[0], "/"),
+		})
+	}
+
+	removedContainers, err := r.getContainersOfRemovedApps(ctx)
+	if err != nil {
+		return Plan{}, err
+	}
+	for _, removedContainer := range removedContainers {
+		plan.Actions = append(plan.Actions, PlanAction{
+			Type:    PlanActionRemove,
+			AppName: removedContainer.Labels[appNameLabel],
+			Target:  strings.TrimPrefix(removedContainer.Names[0], "/"),
+		})
+	}
+
+	return plan, nil
+}
+
+// Whether the actions end with starting the app, reconcile does it only when its dependencies are ready
+func startsApp(actions []PlanAction) bool {
+	for _, action := range actions {
+		switch action.Type {
+		case PlanActionCreate, PlanActionRecreate, PlanActionStart:
+			return true
+		}
+	}
+	return false
+}
+
+// Mirrors [reconcile.createContainers] and [reconcile.startContainers]
+func (r reconcile) planApp(ctx context.Context, app apps.App) ([]PlanAction, error) {
+	configuration := app.Configuration()
+	replicas := getReplicas(configuration)
+
+	appContainers, err := r.dockerClient.ContainerList(ctx, container.ListOptions{
+		All: true,
+		Filters: filters.NewArgs(
+			append(getManagedFilters(r.resourcePrefix), filters.KeyValuePair{Key: "label", Value: appNameLabel + "=" + configuration.AppName})...,
+		),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var replicaActions []PlanAction
+	var removeActions []PlanAction
+	existingReplicas := map[int]bool{}
+	for _, appContainer := range appContainers {
+		replica := getContainerReplica(appContainer)
+		existingReplicas[replica] = true
+		if replica > replicas {
+			removeActions = append(removeActions, PlanAction{
+				Type:    PlanActionRemove,
+				AppName: configuration.AppName,
+				Target:  strings.TrimPrefix(appContainer.Names[0], "/"),
+			})
+		}
+	}
+
+	hasOutdatedReplicas := false
+	for replica := 1; replica <= replicas; replica++ {
+		containerName := r.getContainerName(configuration, replica)
+		spec, err := r.getContainerSpec(configuration, replica)
+		if err != nil {
+			return nil, err
+		}
+
+		upToDateContainers, err := r.dockerClient.ContainerList(ctx, container.ListOptions{
+			All:   true,
+			Limit: 1,
+			Filters: filters.NewArgs(
+				r.getContainerFilters(
+					configuration,
+					replica,
+					[]filters.KeyValuePair{
+						{Key: "label", Value: configurationHashLabel + "=" + spec.hash},
+					},
+				)...,
+			),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(upToDateContainers) > 0 {
+			if upToDateContainers[0].State != "running" {
+				replicaActions = append(replicaActions, PlanAction{Type: PlanActionStart, AppName: configuration.AppName, Target: containerName})
+			}
+			continue
+		}
+
+		hasOutdatedReplicas = true
+		actionType := PlanActionCreate
+		if existingReplicas[replica] {
+			actionType = PlanActionRecreate
+		}
+		replicaActions = append(replicaActions, PlanAction{Type: actionType, AppName: configuration.AppName, Target: containerName})
+	}
+
+	var actions []PlanAction
+	if hasOutdatedReplicas && !app.IsBuilt(ctx) {
+		actions = append(actions, PlanAction{Type: PlanActionBuild, AppName: configuration.AppName, Target: configuration.Image})
+	}
+	actions = append(actions, replicaActions...)
+	return append(actions, removeActions...), nil
+}
@@ -1,14 +1,22 @@
 package containermanager
 
 import (
+	"archive/tar"
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
+	"slices"
+	"strconv"
 	"strings"
 
+	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/volume"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
 	"github.com/krystofrezac/lifebuoy/internal/apps"
 	"github.com/krystofrezac/lifebuoy/internal/queues"
 )
@@ -19,7 +27,14 @@ type reconcile struct {
 	dockerClient   *client.Client
 	buildProcessor *queues.UniqueJobProcessor
 	resourcePrefix string
-	apps           []apps.App
+	// Apps to reconcile, dependencies go first
+	apps []apps.App
+	// All configured apps
+	allApps []apps.App
+	// Containers of other apps are removed
+	keptAppNames []string
+	// Containers of paused apps are stopped
+	pausedAppNames []string
 }
 
 func runReconcile(
@@ -29,7 +44,12 @@ func runReconcile(
 	buildProcessor *queues.UniqueJobProcessor,
 	reconcileFinishChannel chan<- struct{},
 	resourcePrefix string,
-	apps []apps.App,
+	allApps []apps.App,
+	// nil = all apps
+	reconciledAppNames []string,
+	// Apps without a valid definition. Their containers are kept
+	invalidAppNames []string,
+	pausedAppNames []string,
 ) {
 	logger.Debug("Container reconcile started")
 
@@ -39,41 +59,147 @@ func runReconcile(
 		dockerClient:   dockerClient,
 		buildProcessor: buildProcessor,
 		resourcePrefix: resourcePrefix,
-		apps:           apps,
+		apps:           sortByDependencies(filterApps(getUnpausedApps(allApps, pausedAppNames), reconciledAppNames)),
+		allApps:        allApps,
+		keptAppNames:   getKeptAppNames(allApps, invalidAppNames),
+		pausedAppNames: pausedAppNames,
 	}
 
+	r.removeContainersOfRemovedApps(ctx)
+	r.stopContainersOfPausedApps(ctx)
+	r.resolveApps(ctx)
 	r.createContainers(ctx)
 	r.startContainers(ctx)
-
-	// TODO: stop and remove apps that don't exist anymore
-	// TODO: remove unused images
+	r.removeOutdatedImages(ctx)
 
 	logger.Debug("Container reconcile finished")
 	reconcileFinishChannel <- struct{}{}
 }
 
+// Volumes of the removed apps are kept, so the data isn't lost when an app is removed by mistake
+func (r reconcile) removeContainersOfRemovedApps(ctx context.Context) {
+	containers, err := r.getContainersOfRemovedApps(ctx)
+	if err != nil {
+		r.logger.Error("Failed to list containers of removed apps", "err", err)
+		return
+	}
+
+	for _, removedContainer := range containers {
+		appName := removedContainer.Labels[appNameLabel]
+		r.logger.Info("Removing container of removed app", "appName", appName, "containerId", removedContainer.ID)
+
+		err = r.dockerClient.ContainerStop(ctx, removedContainer.ID, container.StopOptions{})
+		if err == nil {
+			err = r.dockerClient.ContainerRemove(ctx, removedContainer.ID, container.RemoveOptions{})
+		}
+		if err != nil {
+			r.logger.Error("Failed to remove container of removed app", "appName", appName, "err", err)
+		}
+	}
+}
+
+func (r reconcile) getContainersOfRemovedApps(ctx context.Context) ([]types.Container, error) {
+	containers, err := r.dockerClient.ContainerList(ctx, container.ListOptions{
+		All:     true,
+		Filters: filters.NewArgs(getManagedFilters(r.resourcePrefix)...),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var res []types.Container
+	for _, managedContainer := range containers {
+		if slices.Contains(r.keptAppNames, managedContainer.Labels[appNameLabel]) {
+			continue
+		}
+		res = append(res, managedContainer)
+	}
+	return res, nil
+}
+
+// Stopped containers stay, so resumed apps start from the same container
+func (r reconcile) stopContainersOfPausedApps(ctx context.Context) {
+	containers, err := r.getRunningContainersOfPausedApps(ctx)
+	if err != nil {
+		r.logger.Error("Failed to list containers of paused apps", "err", err)
+		return
+	}
+
+	for _, pausedContainer := range containers {
+		appName := pausedContainer.Labels[appNameLabel]
+		r.logger.Info("Stopping container of paused app", "appName", appName, "containerId", pausedContainer.ID)
+
+		err = r.dockerClient.ContainerStop(ctx, pausedContainer.ID, container.StopOptions{})
+		if err != nil {
+			r.logger.Error("Failed to stop container of paused app", "appName", appName, "err", err)
+		}
+	}
+}
+
+func (r reconcile) getRunningContainersOfPausedApps(ctx context.Context) ([]types.Container, error) {
+	if len(r.pausedAppNames) == 0 {
+		return nil, nil
+	}
+
+	containers, err := r.dockerClient.ContainerList(ctx, container.ListOptions{
+		Filters: filters.NewArgs(
+			append(getManagedFilters(r.resourcePrefix), filters.KeyValuePair{Key: "status", Value: "running"})...,
+		),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var res []types.Container
+	for _, managedContainer := range containers {
+		if slices.Contains(r.pausedAppNames, managedContainer.Labels[appNameLabel]) {
+			res = append(res, managedContainer)
+		}
+	}
+	return res, nil
+}
+
+// Resources are labeled with the exact resource prefix, so multiple instances of Lifebuoy can share the Docker daemon,
+// even when one prefix starts with the other (e.g. `dev.lifebuoy.` and `dev.lifebuoy.staging.`)
+func getManagedFilters(resourcePrefix string) []filters.KeyValuePair {
+	return []filters.KeyValuePair{
+		{Key: "label", Value: managedLabel},
+		{Key: "label", Value: resourcePrefixLabel + "=" + resourcePrefix},
+	}
+}
+
+// Apps that fail to resolve are skipped, so their current containers keep running
+func (r *reconcile) resolveApps(ctx context.Context) {
+	resolvedApps := make([]apps.App, 0, len(r.apps))
+	for _, app := range r.apps {
+		resolved, err := app.Resolve(ctx)
+		if err != nil {
+			r.logger.Error("Failed to resolve app", "appName", app.Configuration().AppName, "err", err)
+			continue
+		}
+		resolvedApps = append(resolvedApps, resolved)
+	}
+	r.apps = resolvedApps
+}
+
 func (r reconcile) createContainers(ctx context.Context) {
 	for _, app := range r.apps {
 		configuration := app.Configuration()
-		containerName := r.getContainerName(configuration)
 
-		containers, err := r.dockerClient.ContainerList(ctx, container.ListOptions{
-			All:   true,
-			Limit: 1,
-			Filters: filters.NewArgs(
-				getContainerFilters(
-					containerName,
-					configuration.Image,
-					nil,
-				)...,
-			),
-		})
+		// Scaling down doesn't wait for the build
+		err := r.removeSurplusReplicas(ctx, configuration)
 		if err != nil {
-			r.logger.Error("Failed to list containers", "err", err)
+			r.logger.Error("Failed to remove surplus replicas", "appName", configuration.AppName, "err", err)
+			continue
+		}
+
+		outdatedReplicas, err := r.getOutdatedReplicas(ctx, configuration)
+		if err != nil {
+			r.logger.Error("Failed to find outdated replicas", "appName", configuration.AppName, "err", err)
 			continue
 		}
-		if len(containers) != 0 {
-			r.logger.Debug("Container already exists, skipping creation", "appName", configuration.AppName)
+		if len(outdatedReplicas) == 0 {
+			r.logger.Debug("Containers already exist, skipping creation", "appName", configuration.AppName)
 			continue
 		}
 
@@ -85,88 +211,435 @@ func (r reconcile) createContainers(ctx context.Context) {
 			continue
 		}
 
-		r.logger.Info("Creating container", "appName", configuration.AppName)
-		_, err = r.dockerClient.ContainerCreate(
-			ctx,
-			&container.Config{
-				Image: configuration.Image,
-				Labels: map[string]string{
-					managedLabel: "true",
-				},
-			},
-			nil,
-			nil,
-			nil,
-			containerName,
-		)
+		err = r.ensureVolumes(ctx, configuration)
 		if err != nil {
-			r.logger.Error("Failed to create container", "appName", configuration.AppName, "err", err)
+			r.logger.Error("Failed to create volumes", "appName", configuration.AppName, "err", err)
+			continue
+		}
+
+		for _, replica := range outdatedReplicas {
+			r.createContainer(ctx, configuration, replica)
 		}
 	}
 }
 
+// Replicas without a container matching the configuration
+func (r reconcile) getOutdatedReplicas(ctx context.Context, configuration apps.AppConfiguration) ([]int, error) {
+	var outdatedReplicas []int
+	for replica := 1; replica <= getReplicas(configuration); replica++ {
+		spec, err := r.getContainerSpec(configuration, replica)
+		if err != nil {
+			return nil, err
+		}
+
+		containers, err := r.dockerClient.ContainerList(ctx, container.ListOptions{
+			All:   true,
+			Limit: 1,
+			Filters: filters.NewArgs(
+				r.getContainerFilters(
+					configuration,
+					replica,
+					[]filters.KeyValuePair{
+						{Key: "label", Value: configurationHashLabel + "=" + spec.hash},
+					},
+				)...,
+			),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(containers) == 0 {
+			outdatedReplicas = append(outdatedReplicas, replica)
+		}
+	}
+	return outdatedReplicas, nil
+}
+
+// Replaces the current container of the replica
+func (r reconcile) createContainer(ctx context.Context, configuration apps.AppConfiguration, replica int) {
+	spec, err := r.getContainerSpec(configuration, replica)
+	if err != nil {
+		r.logger.Error("Failed to create container spec", "appName", configuration.AppName, "err", err)
+		return
+	}
+
+	err = r.removeAppContainers(ctx, configuration.AppName, func(appContainer types.Container) bool {
+		return getContainerReplica(appContainer) == replica
+	})
+	if err != nil {
+		r.logger.Error("Failed to remove outdated containers", "appName", configuration.AppName, "replica", replica, "err", err)
+		return
+	}
+
+	r.logger.Info("Creating container", "appName", configuration.AppName, "replica", replica)
+	created, err := r.dockerClient.ContainerCreate(
+		ctx,
+		spec.config,
+		spec.hostConfig,
+		nil,
+		nil,
+		r.getContainerName(configuration, replica),
+	)
+	if err != nil {
+		r.logger.Error("Failed to create container", "appName", configuration.AppName, "replica", replica, "err", err)
+		return
+	}
+
+	err = r.copySecretFiles(ctx, created.ID, spec)
+	if err != nil {
+		r.logger.Error("Failed to copy secret files into container", "appName", configuration.AppName, "replica", replica, "err", err)
+
+		// The container would start without its secrets
+		removeErr := r.dockerClient.ContainerRemove(ctx, created.ID, container.RemoveOptions{})
+		if removeErr != nil {
+			r.logger.Error("Failed to remove container", "appName", configuration.AppName, "replica", replica, "err", removeErr)
+		}
+	}
+}
+
+// Containers of replicas above the configured count, e.g. after the app was scaled down
+func (r reconcile) removeSurplusReplicas(ctx context.Context, configuration apps.AppConfiguration) error {
+	replicas := getReplicas(configuration)
+	return r.removeAppContainers(ctx, configuration.AppName, func(appContainer types.Container) bool {
+		return getContainerReplica(appContainer) > replicas
+	})
+}
+
 func (r reconcile) startContainers(ctx context.Context) {
 	for _, app := range r.apps {
 		configuration := app.Configuration()
-		containerName := r.getContainerName(configuration)
+		for replica := 1; replica <= getReplicas(configuration); replica++ {
+			r.startContainer(ctx, configuration, replica)
+		}
+	}
+}
+
+func (r reconcile) startContainer(ctx context.Context, configuration apps.AppConfiguration, replica int) {
+	runningContainers, err := r.dockerClient.ContainerList(ctx, container.ListOptions{
+		Limit: 1,
+		Filters: filters.NewArgs(
+			r.getContainerFilters(
+				configuration,
+				replica,
+				[]filters.KeyValuePair{
+					{Key: "status", Value: "running"},
+				},
+			)...,
+		),
+	})
+	if err != nil {
+		r.logger.Error("Failed to list containers", "err", err)
+		return
+	}
+	if len(runningContainers) > 0 {
+		r.logger.Debug("Container already running, skipping start", "appName", configuration.AppName, "replica", replica)
+		return
+	}
+
+	createdContainers, err := r.dockerClient.ContainerList(ctx, container.ListOptions{
+		All:     true,
+		Limit:   1,
+		Filters: filters.NewArgs(r.getContainerFilters(configuration, replica, nil)...),
+	})
+	if err != nil {
+		r.logger.Error("Failed to list containers", "err", err)
+		return
+	}
+	if len(createdContainers) == 0 {
+		r.logger.Debug("Container doesn't exist yet, skipping start", "appName", configuration.AppName, "replica", replica)
+		return
+	}
+
+	if !r.areDependenciesReady(ctx, configuration) {
+		return
+	}
+
+	err = r.dockerClient.ContainerStart(ctx, createdContainers[0].ID, container.StartOptions{})
+	if err != nil {
+		r.logger.Error("Failed to start container", "err", err, "appName", configuration.AppName, "replica", replica)
+	}
+}
+
+// Images are tagged by the commit sha, so every push to a tracked branch adds one. Images of other revisions are removed
+// once the container of the current one is running. The first replica is enough, the others are created together with it
+func (r reconcile) removeOutdatedImages(ctx context.Context) {
+	for _, app := range r.apps {
+		configuration := app.Configuration()
 
 		runningContainers, err := r.dockerClient.ContainerList(ctx, container.ListOptions{
 			Limit: 1,
 			Filters: filters.NewArgs(
-				getContainerFilters(
-					containerName,
-					configuration.Image,
-					[]filters.KeyValuePair{
-						{Key: "status", Value: "running"},
-					},
+				r.getContainerFilters(
+					configuration,
+					1,
+					[]filters.KeyValuePair{{Key: "status", Value: "running"}},
 				)...,
 			),
 		})
 		if err != nil {
 			r.logger.Error("Failed to list containers", "err", err)
-			return
+			continue
 		}
-		if len(runningContainers) > 0 {
-			r.logger.Debug("Container already running, skipping start", "appName", configuration.AppName)
+		if len(runningContainers) == 0 {
+			continue
 		}
 
-		createdContainers, err := r.dockerClient.ContainerList(ctx, container.ListOptions{
-			All:     true,
-			Limit:   1,
-			Filters: filters.NewArgs(getContainerFilters(containerName, configuration.Image, nil)...),
+		repository := getImageRepository(configuration.Image)
+		images, err := r.dockerClient.ImageList(ctx, image.ListOptions{
+			Filters: filters.NewArgs(
+				filters.KeyValuePair{Key: "label", Value: managedLabel},
+				filters.KeyValuePair{Key: "reference", Value: repository},
+			),
 		})
 		if err != nil {
-			r.logger.Error("Failed to list containers", "err", err)
-			return
+			r.logger.Error("Failed to list images", "appName", configuration.AppName, "err", err)
+			continue
 		}
-		if len(createdContainers) == 0 {
-			r.logger.Debug("Container doesn't exist yet, skipping start", "appName", configuration.AppName)
-			return
+
+		for _, outdatedImage := range images {
+			for _, tag := range outdatedImage.RepoTags {
+				if tag == configuration.Image || getImageRepository(tag) != repository {
+					continue
+				}
+
+				r.logger.Info("Removing outdated image", "appName", configuration.AppName, "image", tag)
+				_, err = r.dockerClient.ImageRemove(ctx, tag, image.RemoveOptions{PruneChildren: true})
+				if err != nil {
+					r.logger.Error("Failed to remove outdated image", "appName", configuration.AppName, "image", tag, "err", err)
+				}
+			}
 		}
+	}
+}
 
-		err = r.dockerClient.ContainerStart(ctx, containerName, container.StartOptions{})
+// Image reference without the tag
+func getImageRepository(reference string) string {
+	tagStart := strings.LastIndex(reference, ":")
+	if tagStart == -1 || strings.Contains(reference[tagStart:], "/") {
+		return reference
+	}
+	return reference[:tagStart]
+}
+
+// Dependencies started by this reconcile are usually not healthy yet, the app is then started by one of the next reconciles
+func (r reconcile) areDependenciesReady(ctx context.Context, configuration apps.AppConfiguration) bool {
+	dependency, err := r.getUnreadyDependency(ctx, configuration)
+	if err != nil {
+		r.logger.Error("Failed to check dependency", "appName", configuration.AppName, "dependency", dependency, "err", err)
+		return false
+	}
+	if dependency != "" {
+		r.logger.Info("Waiting for dependency, skipping start", "appName", configuration.AppName, "dependency", dependency)
+		return false
+	}
+	return true
+}
+
+// Returns the first dependency that isn't ready, empty when all are
+func (r reconcile) getUnreadyDependency(ctx context.Context, configuration apps.AppConfiguration) (string, error) {
+	for _, dependency := range configuration.DependsOn {
+		ready, err := r.isAppReady(ctx, dependency)
+		if err != nil || !ready {
+			return dependency, err
+		}
+	}
+	return "", nil
+}
+
+// App is ready when its container is running, and healthy when it has a healthcheck
+func (r reconcile) isAppReady(ctx context.Context, appName string) (bool, error) {
+	filterArgs := filters.NewArgs(
+		append(
+			getManagedFilters(r.resourcePrefix),
+			filters.KeyValuePair{Key: "label", Value: appNameLabel + "=" + appName},
+			filters.KeyValuePair{Key: "status", Value: "running"},
+		)...,
+	)
+	for _, app := range r.allApps {
+		if app.Configuration().AppName == appName && app.Configuration().Healthcheck != nil {
+			filterArgs.Add("health", "healthy")
+		}
+	}
+
+	containers, err := r.dockerClient.ContainerList(ctx, container.ListOptions{Limit: 1, Filters: filterArgs})
+	if err != nil {
+		return false, err
+	}
+	return len(containers) > 0, nil
+}
+
+// Creates managed volumes that don't exist yet. Existing volumes are kept as they are, so the data survive container re-creation
+func (r reconcile) ensureVolumes(ctx context.Context, configuration apps.AppConfiguration) error {
+	for _, appVolume := range configuration.Volumes {
+		if appVolume.Type != apps.VolumeTypeManaged {
+			continue
+		}
+
+		// Creating already existing volume is no-op
+		_, err := r.dockerClient.VolumeCreate(ctx, volume.CreateOptions{
+			Name: r.getVolumeName(configuration.AppName, appVolume.Name),
+			Labels: map[string]string{
+				managedLabel:        "true",
+				resourcePrefixLabel: r.resourcePrefix,
+				appNameLabel:        configuration.AppName,
+			},
+		})
 		if err != nil {
-			r.logger.Error("Failed to start container", "err", err, "appName", configuration.AppName)
-			return
+			return err
 		}
 	}
+
+	return nil
 }
 
-func (r reconcile) getContainerName(configuration apps.AppConfiguration) string {
+// Secret files are copied directly into the container, so their content is never stored on the host
+func (r reconcile) copySecretFiles(ctx context.Context, containerId string, spec containerSpec) error {
+	if len(spec.secretFiles) == 0 {
+		return nil
+	}
+
+	owner, err := r.getContainerFileOwner(ctx, containerId)
+	if err != nil {
+		return fmt.Errorf("Failed to find owner of secret files: %w", err)
+	}
+
+	archive, err := getSecretFilesArchive(spec.secretFiles, owner)
+	if err != nil {
+		return err
+	}
+
+	return r.dockerClient.CopyToContainer(ctx, containerId, "/", archive, container.CopyToContainerOptions{})
+}
+
+// User of the container, by default from the image. Names are looked up in files of the container
+func (r reconcile) getContainerFileOwner(ctx context.Context, containerId string) (fileOwner, error) {
+	inspected, err := r.dockerClient.ContainerInspect(ctx, containerId)
+	if err != nil {
+		return fileOwner{}, err
+	}
+
+	user := inspected.Config.User
+	if user == "" {
+		return fileOwner{}, nil
+	}
+
+	passwd, err := r.readContainerFile(ctx, containerId, "/etc/passwd")
+	if err != nil {
+		return fileOwner{}, err
+	}
+	group, err := r.readContainerFile(ctx, containerId, "/etc/group")
+	if err != nil {
+		return fileOwner{}, err
+	}
+	return getFileOwner(user, passwd, group)
+}
+
+// Empty when the file doesn't exist, e.g. in images from scratch
+func (r reconcile) readContainerFile(ctx context.Context, containerId string, filePath string) (string, error) {
+	reader, _, err := r.dockerClient.CopyFromContainer(ctx, containerId, filePath)
+	if errdefs.IsNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	archive := tar.NewReader(reader)
+	_, err = archive.Next()
+	if err != nil {
+		return "", err
+	}
+	content, err := io.ReadAll(archive)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// Stops and removes containers of the app for which shouldRemove returns true. Their volumes are kept
+func (r reconcile) removeAppContainers(ctx context.Context, appName string, shouldRemove func(types.Container) bool) error {
+	// Containers created before the resource prefix label have only the others
+	containers, err := r.dockerClient.ContainerList(ctx, container.ListOptions{
+		All: true,
+		Filters: filters.NewArgs(
+			filters.KeyValuePair{Key: "label", Value: managedLabel},
+			filters.KeyValuePair{Key: "label", Value: appNameLabel + "=" + appName},
+		),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, outdatedContainer := range containers {
+		if !r.isContainerOfApp(outdatedContainer, appName) || !shouldRemove(outdatedContainer) {
+			continue
+		}
+
+		r.logger.Info("Removing container", "appName", appName, "containerId", outdatedContainer.ID)
+
+		err = r.dockerClient.ContainerStop(ctx, outdatedContainer.ID, container.StopOptions{})
+		if err != nil {
+			return err
+		}
+
+		err = r.dockerClient.ContainerRemove(ctx, outdatedContainer.ID, container.RemoveOptions{})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Containers without the resource prefix label are recognized by their name
+func (r reconcile) isContainerOfApp(managedContainer types.Container, appName string) bool {
+	if resourcePrefix, ok := managedContainer.Labels[resourcePrefixLabel]; ok {
+		return resourcePrefix == r.resourcePrefix
+	}
+	for _, name := range managedContainer.Names {
+		// Names start with `/`
+		if strings.HasPrefix(strings.TrimPrefix(name, "/"), r.resourcePrefix+appName+"_") {
+			return true
+		}
+	}
+	return false
+}
+
+func (r reconcile) getVolumeName(appName string, volumeName string) string {
+	return fmt.Sprintf("%s%s_%s", r.resourcePrefix, appName, volumeName)
+}
+
+func (r reconcile) getContainerName(configuration apps.AppConfiguration, replica int) string {
 	imageVersion := ""
 	if split := strings.Split(configuration.Image, ":"); len(split) > 0 {
 		imageVersion = split[1]
 	}
 
-	return fmt.Sprintf("%s%s_%s", r.resourcePrefix, configuration.AppName, imageVersion)
+	return fmt.Sprintf("%s%s_%s_%d", r.resourcePrefix, configuration.AppName, imageVersion, replica)
 }
 
-func getContainerFilters(containerName string, image string, additional []filters.KeyValuePair) []filters.KeyValuePair {
-	res := []filters.KeyValuePair{
-		{Key: "label", Value: managedLabel},
-		{Key: "name", Value: containerName},
-		{Key: "ancestor", Value: image},
+func getReplicas(configuration apps.AppConfiguration) int {
+	return max(configuration.Replicas, 1)
+}
+
+// Containers created before replicas were introduced are the first replica
+func getContainerReplica(appContainer types.Container) int {
+	replica, err := strconv.Atoi(appContainer.Labels[replicaLabel])
+	if err != nil {
+		return 1
 	}
+	return replica
+}
+
+// Docker matches names by substring, the replica label tells the replicas apart
+func (r reconcile) getContainerFilters(configuration apps.AppConfiguration, replica int, additional []filters.KeyValuePair) []filters.KeyValuePair {
+	res := append(
+		getManagedFilters(r.resourcePrefix),
+		filters.KeyValuePair{Key: "name", Value: r.getContainerName(configuration, replica)},
+		filters.KeyValuePair{Key: "ancestor", Value: configuration.Image},
+		filters.KeyValuePair{Key: "label", Value: replicaLabel + "=" + strconv.Itoa(replica)},
+	)
 	res = append(res, additional...)
 	return res
 }
@@ -0,0 +1,116 @@
+package containermanager
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/krystofrezac/lifebuoy/internal/apps"
+)
+
+func TestGetImageRepository(t *testing.T) {
+	tests := []struct {
+		reference string
+		expected  string
+	}{
+		{"dev.lifebuoy.app:0123456789abcdef0123456789abcdef01234567", "dev.lifebuoy.app"},
+		{"registry.example.com:5000/app:main", "registry.example.com:5000/app"},
+		{"registry.example.com:5000/app", "registry.example.com:5000/app"},
+		{"app", "app"},
+	}
+
+	for _, test := range tests {
+		res := getImageRepository(test.reference)
+		if res != test.expected {
+			t.Fatalf("Expected '%s' for '%s', got '%s'", test.expected, test.reference, res)
+		}
+	}
+}
+
+func TestGetManagedFilters_NestedPrefixes(t *testing.T) {
+	configuration := apps.AppConfiguration{AppName: "web", Image: "dev.lifebuoy.staging.web:main"}
+	spec, err := reconcile{resourcePrefix: "dev.lifebuoy.staging."}.getContainerSpec(configuration, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !filters.NewArgs(getManagedFilters("dev.lifebuoy.staging.")...).MatchKVList("label", spec.config.Labels) {
+		t.Fatal("Expected container to belong to its instance")
+	}
+	if filters.NewArgs(getManagedFilters("dev.lifebuoy.")...).MatchKVList("label", spec.config.Labels) {
+		t.Fatal("Expected container not to belong to the instance with the shorter prefix")
+	}
+}
+
+func TestIsContainerOfApp_NestedPrefixes(t *testing.T) {
+	r := reconcile{resourcePrefix: "dev.lifebuoy."}
+
+	tests := []struct {
+		name      string
+		container types.Container
+		expected  bool
+	}{
+		{
+			name:      "labeled",
+			container: types.Container{Names: []string{"/dev.lifebuoy.web_abc"}, Labels: map[string]string{resourcePrefixLabel: "dev.lifebuoy."}},
+			expected:  true,
+		},
+		{
+			name:      "labeled by other instance",
+			container: types.Container{Names: []string{"/dev.lifebuoy.staging.web_abc"}, Labels: map[string]string{resourcePrefixLabel: "dev.lifebuoy.staging."}},
+			expected:  false,
+		},
+		{
+			name:      "unlabeled",
+			container: types.Container{Names: []string{"/dev.lifebuoy.web_abc"}, Labels: map[string]string{}},
+			expected:  true,
+		},
+		{
+			name:      "unlabeled of other instance",
+			container: types.Container{Names: []string{"/dev.lifebuoy.staging.web_abc"}, Labels: map[string]string{}},
+			expected:  false,
+		},
+	}
+
+	for _, test := range tests {
+		if res := r.isContainerOfApp(test.container, "web"); res != test.expected {
+			t.Fatalf("%s: expected %t, got %t", test.name, test.expected, res)
+		}
+	}
+}
+
+func TestGetContainerFilters_MatchOnlyTheirReplica(t *testing.T) {
+	r := reconcile{resourcePrefix: "dev.lifebuoy."}
+	configuration := apps.AppConfiguration{AppName: "web", Image: "dev.lifebuoy.web:abc", Replicas: 2}
+
+	for replica := 1; replica <= 2; replica++ {
+		spec, err := r.getContainerSpec(configuration, replica)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for otherReplica := 1; otherReplica <= 2; otherReplica++ {
+			matches := filters.NewArgs(r.getContainerFilters(configuration, otherReplica, nil)...).MatchKVList("label", spec.config.Labels)
+			if matches != (replica == otherReplica) {
+				t.Fatalf("Expected filters of replica %d to match container of replica %d: %t, got %t", otherReplica, replica, replica == otherReplica, matches)
+			}
+		}
+	}
+}
+
+func TestGetContainerReplica(t *testing.T) {
+	tests := []struct {
+		name     string
+		labels   map[string]string
+		expected int
+	}{
+		{"labeled", map[string]string{replicaLabel: "3"}, 3},
+		{"created before replicas", map[string]string{}, 1},
+	}
+
+	for _, test := range tests {
+		if res := getContainerReplica(types.Container{Labels: test.labels}); res != test.expected {
+			t.Fatalf("%s: expected %d, got %d", test.name, test.expected, res)
+		}
+	}
+}
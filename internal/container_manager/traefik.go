@@ -0,0 +1,58 @@
+package containermanager
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+
+	"github.com/krystofrezac/lifebuoy/internal/apps"
+)
+
+var traefikNameInvalidCharsRegex = regexp.MustCompile("[^a-zA-Z0-9-]")
+
+// Labels for the Traefik Docker provider that route traffic to the container
+func getTraefikLabels(resourcePrefix string, appName string, routes []apps.Route) map[string]string {
+	if len(routes) == 0 {
+		return map[string]string{}
+	}
+
+	labels := map[string]string{
+		"traefik.enable": "true",
+	}
+
+	baseName := getTraefikBaseName(resourcePrefix, appName)
+	for i, route := range routes {
+		name := fmt.Sprintf("%s-%d", baseName, i)
+		router := "traefik.http.routers." + name
+
+		rule := fmt.Sprintf("Host(`%s`)", route.Host)
+		if route.PathPrefix != "/" {
+			rule += fmt.Sprintf(" && PathPrefix(`%s`)", route.PathPrefix)
+
+			middleware := name + "-stripprefix"
+			labels["traefik.http.middlewares."+middleware+".stripprefix.prefixes"] = route.PathPrefix
+			labels[router+".middlewares"] = middleware
+		}
+
+		labels[router+".rule"] = rule
+		labels[router+".entrypoints"] = apps.TraefikEntrypoint
+		labels[router+".service"] = name
+		labels["traefik.http.services."+name+".loadbalancer.server.port"] = fmt.Sprint(route.Port)
+	}
+
+	return labels
+}
+
+// Invalid characters are replaced, so different app names could end up the same (e.g. `my_app` and `my.app`).
+// Short hash of the original name is appended to the names that had to be changed
+func getTraefikBaseName(resourcePrefix string, appName string) string {
+	prefix := traefikNameInvalidCharsRegex.ReplaceAllString(resourcePrefix, "-")
+	sanitizedAppName := traefikNameInvalidCharsRegex.ReplaceAllString(appName, "-")
+	if sanitizedAppName == appName {
+		return prefix + appName
+	}
+
+	hash := sha256.Sum256([]byte(appName))
+	return fmt.Sprintf("%s%s-%s", prefix, sanitizedAppName, hex.EncodeToString(hash[:4]))
+}
@@ -0,0 +1,58 @@
+package containermanager
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/krystofrezac/lifebuoy/internal/apps"
+)
+
+func TestGetTraefikLabels_NoRoutes(t *testing.T) {
+	labels := getTraefikLabels("dev.lifebuoy.", "app", nil)
+	if len(labels) != 0 {
+		t.Fatalf("Expected no labels, got %+v", labels)
+	}
+}
+
+func TestGetTraefikLabels_Routes(t *testing.T) {
+	labels := getTraefikLabels("dev.lifebuoy.", "app", []apps.Route{
+		{Port: 8080, Host: "example.com", PathPrefix: "/"},
+		{Port: 3000, Host: "example.com", PathPrefix: "/api"},
+	})
+
+	expected := map[string]string{
+		"traefik.enable": "true",
+
+		"traefik.http.routers.dev-lifebuoy-app-0.rule":                      "Host(`example.com`)",
+		"traefik.http.routers.dev-lifebuoy-app-0.entrypoints":               "web",
+		"traefik.http.routers.dev-lifebuoy-app-0.service":                   "dev-lifebuoy-app-0",
+		"traefik.http.services.dev-lifebuoy-app-0.loadbalancer.server.port": "8080",
+
+		"traefik.http.routers.dev-lifebuoy-app-1.rule":                                 "Host(`example.com`) && PathPrefix(`/api`)",
+		"traefik.http.routers.dev-lifebuoy-app-1.entrypoints":                          "web",
+		"traefik.http.routers.dev-lifebuoy-app-1.service":                              "dev-lifebuoy-app-1",
+		"traefik.http.routers.dev-lifebuoy-app-1.middlewares":                          "dev-lifebuoy-app-1-stripprefix",
+		"traefik.http.middlewares.dev-lifebuoy-app-1-stripprefix.stripprefix.prefixes": "/api",
+		"traefik.http.services.dev-lifebuoy-app-1.loadbalancer.server.port":            "3000",
+	}
+	if !reflect.DeepEqual(labels, expected) {
+		t.Fatalf("Expected %+v, got %+v", expected, labels)
+	}
+}
+
+func TestGetTraefikLabels_SanitizedNamesDontCollide(t *testing.T) {
+	routes := []apps.Route{{Port: 80, Host: "example.com", PathPrefix: "/"}}
+	names := map[string]string{}
+	for _, appName := range []string{"my_app", "my.app", "my-app"} {
+		for label := range getTraefikLabels("dev.lifebuoy.", appName, routes) {
+			if !strings.HasSuffix(label, ".rule") {
+				continue
+			}
+			if other, ok := names[label]; ok {
+				t.Fatalf("Apps `%s` and `%s` have the same router `%s`", other, appName, label)
+			}
+			names[label] = appName
+		}
+	}
+}
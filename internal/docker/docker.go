@@ -2,6 +2,7 @@ package docker
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha256"
 	"errors"
 	"fmt"
@@ -36,12 +37,21 @@ type containerInfo struct {
 	optsSha string
 }
 
-func (conf Docker) BuildImage(name string, dir string) error {
-	stdout, stderr, err := runCommand(
-		"docker", "build", dir,
+// dockerfilePath: path to the Dockerfile, it doesn't have to be inside of contextDir
+//
+// The build is killed when ctx is cancelled
+func (conf Docker) BuildImage(ctx context.Context, name string, contextDir string, dockerfilePath string) error {
+	stdout, stderr, err := runCommandContext(
+		ctx,
+		"docker", "build", contextDir,
+		"--file", dockerfilePath,
 		"--tag", name,
 		"--label", managedLabel,
 	)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		conf.Logger.Error("Build cancelled", "err", ctxErr, "stdout", stdout.String(), "stderr", stderr.String())
+		return ctxErr
+	}
 	if err != nil {
 		conf.Logger.Error("Build failed", "stdout", stdout.String(), "stderr", stderr.String())
 		return err
@@ -163,10 +173,15 @@ func RemoveContainer(name string) error {
 
 // TODO: context
 func runCommand(name string, arg ...string) (bytes.Buffer, bytes.Buffer, error) {
+	return runCommandContext(context.Background(), name, arg...)
+}
+
+// The process is killed when ctx is cancelled
+func runCommandContext(ctx context.Context, name string, arg ...string) (bytes.Buffer, bytes.Buffer, error) {
 	var stdout bytes.Buffer
 	var stderr bytes.Buffer
 
-	cmd := exec.Command(name, arg...)
+	cmd := exec.CommandContext(ctx, name, arg...)
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 	err := cmd.Run()
@@ -62,6 +62,7 @@ func (u *UniqueJobProcessor) Start() {
 
 		case processorPoolSize := <-u.setProcessorPoolSizeChannel:
 			u.processorPoolSize = processorPoolSize
+			u.fillProcessors()
 		}
 	}
 }
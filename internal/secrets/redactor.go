@@ -0,0 +1,103 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// Shorter values would redact unrelated parts of the logs
+const minRedactedLength = 4
+
+// Keeps plain texts of decrypted secrets, so they can be removed from arbitrary strings (e.g. build output)
+type Redactor struct {
+	mutex      sync.RWMutex
+	plaintexts map[string]struct{}
+}
+
+func NewRedactor() *Redactor {
+	return &Redactor{plaintexts: map[string]struct{}{}}
+}
+
+func (r *Redactor) Register(value Value) {
+	if len(value.plaintext) < minRedactedLength {
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.plaintexts[value.plaintext] = struct{}{}
+}
+
+func (r *Redactor) Redact(text string) string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for plaintext := range r.plaintexts {
+		text = strings.ReplaceAll(text, plaintext, redactedPlaceholder)
+	}
+	return text
+}
+
+// Redacts registered secrets from messages and attributes before passing the records to the wrapped handler
+type RedactingHandler struct {
+	handler  slog.Handler
+	redactor *Redactor
+}
+
+func NewRedactingHandler(handler slog.Handler, redactor *Redactor) RedactingHandler {
+	return RedactingHandler{handler: handler, redactor: redactor}
+}
+
+func (h RedactingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+func (h RedactingHandler) Handle(ctx context.Context, record slog.Record) error {
+	redacted := slog.NewRecord(record.Time, record.Level, h.redactor.Redact(record.Message), record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		redacted.AddAttrs(h.redactAttr(attr))
+		return true
+	})
+	return h.handler.Handle(ctx, redacted)
+}
+
+func (h RedactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, 0, len(attrs))
+	for _, attr := range attrs {
+		redacted = append(redacted, h.redactAttr(attr))
+	}
+	return RedactingHandler{handler: h.handler.WithAttrs(redacted), redactor: h.redactor}
+}
+
+func (h RedactingHandler) WithGroup(name string) slog.Handler {
+	return RedactingHandler{handler: h.handler.WithGroup(name), redactor: h.redactor}
+}
+
+func (h RedactingHandler) redactAttr(attr slog.Attr) slog.Attr {
+	value := attr.Value.Resolve()
+
+	switch value.Kind() {
+	case slog.KindString:
+		return slog.String(attr.Key, h.redactor.Redact(value.String()))
+
+	case slog.KindGroup:
+		groupAttrs := value.Group()
+		redacted := make([]any, 0, len(groupAttrs))
+		for _, groupAttr := range groupAttrs {
+			redacted = append(redacted, h.redactAttr(groupAttr))
+		}
+		return slog.Group(attr.Key, redacted...)
+
+	case slog.KindAny:
+		// Errors and other values are formatted by the handler anyway
+		formatted := fmt.Sprint(value.Any())
+		if redacted := h.redactor.Redact(formatted); redacted != formatted {
+			return slog.String(attr.Key, redacted)
+		}
+	}
+
+	return slog.Attr{Key: attr.Key, Value: value}
+}
@@ -0,0 +1,153 @@
+package secrets
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"golang.org/x/crypto/nacl/box"
+)
+
+const encryptedValuePrefix = "lifebuoy-secret:v1:"
+const publicKeyPrefix = "lifebuoy-public-key:"
+const privateKeyPrefix = "lifebuoy-private-key:"
+
+const redactedPlaceholder = "[REDACTED]"
+
+var InvalidKeyError = errors.New("Invalid key")
+var InvalidEncryptedValueError = errors.New("Invalid encrypted value")
+var DecryptionFailedError = errors.New("Failed to decrypt value, it was probably encrypted for a different key")
+
+// Public part of the key is stored next to the private part, so it's not needed separately for decryption
+type PrivateKey struct {
+	public  [32]byte
+	private [32]byte
+}
+
+type PublicKey struct {
+	key [32]byte
+}
+
+// Decrypted secret. It's never printed or logged in plain text, use [Value.Reveal] to get the plain text
+type Value struct {
+	plaintext string
+}
+
+func GenerateKey() (PrivateKey, error) {
+	public, private, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		return PrivateKey{}, err
+	}
+	return PrivateKey{public: *public, private: *private}, nil
+}
+
+func ParsePrivateKey(encoded string) (PrivateKey, error) {
+	decoded, err := decodeKey(encoded, privateKeyPrefix, 64)
+	if err != nil {
+		return PrivateKey{}, err
+	}
+
+	key := PrivateKey{}
+	copy(key.public[:], decoded[:32])
+	copy(key.private[:], decoded[32:])
+	return key, nil
+}
+
+func (k PrivateKey) Encode() string {
+	return privateKeyPrefix + base64.StdEncoding.EncodeToString(append(k.public[:], k.private[:]...))
+}
+
+func (k PrivateKey) PublicKey() PublicKey {
+	return PublicKey{key: k.public}
+}
+
+func (k PrivateKey) Decrypt(encrypted string) (Value, error) {
+	encoded, ok := strings.CutPrefix(strings.TrimSpace(encrypted), encryptedValuePrefix)
+	if !ok {
+		return Value{}, InvalidEncryptedValueError
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return Value{}, InvalidEncryptedValueError
+	}
+
+	plaintext, ok := box.OpenAnonymous(nil, sealed, &k.public, &k.private)
+	if !ok {
+		return Value{}, DecryptionFailedError
+	}
+	return Value{plaintext: string(plaintext)}, nil
+}
+
+func ParsePublicKey(encoded string) (PublicKey, error) {
+	decoded, err := decodeKey(encoded, publicKeyPrefix, 32)
+	if err != nil {
+		return PublicKey{}, err
+	}
+
+	key := PublicKey{}
+	copy(key.key[:], decoded)
+	return key, nil
+}
+
+func (k PublicKey) Encode() string {
+	return publicKeyPrefix + base64.StdEncoding.EncodeToString(k.key[:])
+}
+
+func (k PublicKey) Encrypt(plaintext []byte) (string, error) {
+	sealed, err := box.SealAnonymous(nil, plaintext, &k.key, rand.Reader)
+	if err != nil {
+		return "", err
+	}
+	return encryptedValuePrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Checks only the format, not whether it can be decrypted
+func IsEncryptedValue(value string) bool {
+	encoded, ok := strings.CutPrefix(strings.TrimSpace(value), encryptedValuePrefix)
+	if !ok {
+		return false
+	}
+	_, err := base64.StdEncoding.DecodeString(encoded)
+	return err == nil
+}
+
+// For secrets that don't come from the configuration, e.g. flags
+func NewValue(plaintext string) Value {
+	return Value{plaintext: plaintext}
+}
+
+func (v Value) Reveal() string {
+	return v.plaintext
+}
+
+func (v Value) String() string {
+	return redactedPlaceholder
+}
+
+func (v Value) GoString() string {
+	return redactedPlaceholder
+}
+
+func (v Value) LogValue() slog.Value {
+	return slog.StringValue(redactedPlaceholder)
+}
+
+func (v Value) MarshalText() ([]byte, error) {
+	return []byte(redactedPlaceholder), nil
+}
+
+func decodeKey(encoded string, prefix string, size int) ([]byte, error) {
+	withoutPrefix, ok := strings.CutPrefix(strings.TrimSpace(encoded), prefix)
+	if !ok {
+		return nil, fmt.Errorf("%w, expected prefix `%s`", InvalidKeyError, prefix)
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(withoutPrefix)
+	if err != nil || len(decoded) != size {
+		return nil, InvalidKeyError
+	}
+	return decoded, nil
+}
@@ -0,0 +1,79 @@
+package secrets
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	privateKey, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encrypted, err := privateKey.PublicKey().Encrypt([]byte("db-password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncryptedValue(encrypted) {
+		t.Fatalf("Expected `%s` to be recognized as encrypted value", encrypted)
+	}
+
+	parsedKey, err := ParsePrivateKey(privateKey.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	decrypted, err := parsedKey.Decrypt(encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decrypted.Reveal() != "db-password" {
+		t.Fatalf("Expected `db-password`, got `%s`", decrypted.Reveal())
+	}
+}
+
+func TestDecrypt_DifferentKey(t *testing.T) {
+	privateKey, _ := GenerateKey()
+	otherKey, _ := GenerateKey()
+
+	encrypted, err := privateKey.PublicKey().Encrypt([]byte("db-password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = otherKey.Decrypt(encrypted)
+	if err != DecryptionFailedError {
+		t.Fatalf("Expected DecryptionFailedError, got %v", err)
+	}
+}
+
+func TestValue_IsNotPrinted(t *testing.T) {
+	value := Value{plaintext: "db-password"}
+
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+	logger.Info("message", "value", value, "struct", struct{ Value Value }{value})
+
+	formatted := fmt.Sprintf("%v %+v %#v %s", value, value, value, value) + logs.String()
+	if strings.Contains(formatted, "db-password") {
+		t.Fatalf("Secret leaked: %s", formatted)
+	}
+}
+
+func TestRedactingHandler(t *testing.T) {
+	redactor := NewRedactor()
+	redactor.Register(Value{plaintext: "db-password"})
+
+	var logs bytes.Buffer
+	logger := slog.New(NewRedactingHandler(slog.NewTextHandler(&logs, nil), redactor))
+	logger.
+		With("with", "with db-password").
+		Info("message db-password", "output", "Step 1: echo db-password", "err", fmt.Errorf("failed db-password"))
+
+	if strings.Contains(logs.String(), "db-password") {
+		t.Fatalf("Secret leaked: %s", logs.String())
+	}
+}
@@ -0,0 +1,117 @@
+package sources
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/krystofrezac/lifebuoy/internal/secrets"
+	"gopkg.in/yaml.v3"
+)
+
+// Token of the server together with the instance of the provider it's sent to
+type Credential struct {
+	Token TokenSource
+	Type  ProviderType
+	// Empty = the public instance of the provider
+	BaseUrl string
+}
+
+// Tokens of the server, so they don't have to be in the configuration
+type Credentials struct {
+	// Apps reference them by name, e.g. one per organization
+	Named map[string]Credential
+	// Used by apps without their own token. By provider type
+	Default map[ProviderType]Credential
+}
+
+// Returns nil = the app has no token. Tokens are sent only to the instance they belong to, otherwise anyone who can change
+// the configuration could get them by pointing baseUrl of an app to their server
+func (c Credentials) GetToken(providerType ProviderType, baseUrl string, name string) (TokenSource, error) {
+	if name != "" {
+		credential, ok := c.Named[name]
+		if !ok {
+			return nil, fmt.Errorf("Credentials `%s` don't exist", name)
+		}
+		if credential.Type != providerType || !isSameInstance(providerType, credential.BaseUrl, baseUrl) {
+			return nil, fmt.Errorf("Credentials `%s` can be used only with %s at base URL `%s`", name, credential.Type, getBaseUrl(credential.Type, credential.BaseUrl))
+		}
+		return credential.Token, nil
+	}
+
+	credential, ok := c.Default[providerType]
+	if !ok || !isSameInstance(providerType, credential.BaseUrl, baseUrl) {
+		return nil, nil
+	}
+	return credential.Token, nil
+}
+
+func isSameInstance(providerType ProviderType, baseUrl string, otherBaseUrl string) bool {
+	return strings.EqualFold(getBaseUrl(providerType, baseUrl), getBaseUrl(providerType, otherBaseUrl))
+}
+
+// Empty = the public instance
+func getBaseUrl(providerType ProviderType, baseUrl string) string {
+	baseUrl = strings.TrimSuffix(baseUrl, "/")
+	if baseUrl != "" {
+		return baseUrl
+	}
+
+	switch providerType {
+	case ProviderGithub:
+		return defaultGithubBaseUrl
+	case ProviderGitlab:
+		return defaultGitlabBaseUrl
+	}
+	return ""
+}
+
+// Token alone is a credential of github.com
+type credentialsFileEntry struct {
+	Token   string       `yaml:"token"`
+	Type    ProviderType `yaml:"type"`
+	BaseUrl string       `yaml:"baseUrl"`
+}
+
+func (c *credentialsFileEntry) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		c.Type = ProviderGithub
+		return node.Decode(&c.Token)
+	}
+
+	type plain credentialsFileEntry
+	return node.Decode((*plain)(c))
+}
+
+// File is a YAML map of credentials names to `{token, type, baseUrl}` or just the token. Tokens are registered in the redactor, so they don't appear in logs
+func ReadCredentialsFile(filePath string, redactor *secrets.Redactor) (map[string]Credential, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries map[string]credentialsFileEntry
+	err = yaml.Unmarshal(content, &entries)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse credentials file `%s`: %w", filePath, err)
+	}
+
+	credentials := make(map[string]Credential, len(entries))
+	for name, entry := range entries {
+		if entry.Token == "" {
+			return nil, fmt.Errorf("Credentials `%s` in file `%s` have empty token", name, filePath)
+		}
+		switch entry.Type {
+		case ProviderGithub, ProviderGitlab, ProviderGitea:
+		default:
+			return nil, fmt.Errorf("Credentials `%s` in file `%s` have to have `type` one of: github, gitlab, gitea", name, filePath)
+		}
+		if entry.Type == ProviderGitea && entry.BaseUrl == "" {
+			return nil, fmt.Errorf("Credentials `%s` in file `%s` have to have `baseUrl`, Gitea has no public instance", name, filePath)
+		}
+		value := secrets.NewValue(entry.Token)
+		redactor.Register(value)
+		credentials[name] = Credential{Token: NewStaticToken(value), Type: entry.Type, BaseUrl: entry.BaseUrl}
+	}
+	return credentials, nil
+}
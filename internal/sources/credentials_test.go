@@ -0,0 +1,94 @@
+package sources
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/krystofrezac/lifebuoy/internal/secrets"
+)
+
+func TestCredentials_GetToken(t *testing.T) {
+	credentialsFile := filepath.Join(t.TempDir(), "credentials.yaml")
+	content := "acme: acme-token\n" +
+		"enterprise:\n  token: enterprise-token\n  type: github\n  baseUrl: https://github.example.com/api/v3\n" +
+		"gitlab:\n  token: gitlab-token\n  type: gitlab\n"
+	err := os.WriteFile(credentialsFile, []byte(content), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	named, err := ReadCredentialsFile(credentialsFile, secrets.NewRedactor())
+	if err != nil {
+		t.Fatal(err)
+	}
+	credentials := Credentials{
+		Named:   named,
+		Default: map[ProviderType]Credential{ProviderGithub: {Token: NewStaticToken(secrets.NewValue("server-token"))}},
+	}
+
+	tests := []struct {
+		providerType ProviderType
+		baseUrl      string
+		name         string
+		expected     string
+	}{
+		{ProviderGithub, "", "acme", "acme-token"},
+		{ProviderGitlab, "", "gitlab", "gitlab-token"},
+		{ProviderGithub, "https://github.example.com/api/v3/", "enterprise", "enterprise-token"},
+		{ProviderGithub, "", "", "server-token"},
+		{ProviderGithub, "https://api.github.com", "", "server-token"},
+		{ProviderGithub, "https://attacker.example.com", "", ""},
+		{ProviderGitlab, "", "", ""},
+	}
+	for _, test := range tests {
+		token, err := credentials.GetToken(test.providerType, test.baseUrl, test.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res := ""
+		if token != nil {
+			value, _ := token.Token(context.Background())
+			res = value.Reveal()
+		}
+		if res != test.expected {
+			t.Fatalf("Expected '%s' for `%s`, `%s` and `%s`, got '%s'", test.expected, test.providerType, test.baseUrl, test.name, res)
+		}
+	}
+
+	_, err = credentials.GetToken(ProviderGithub, "", "other")
+	if err == nil {
+		t.Fatal("Expected error for unknown credentials")
+	}
+	_, err = credentials.GetToken(ProviderGithub, "https://attacker.example.com", "acme")
+	if err == nil {
+		t.Fatal("Expected error for credentials of other instance")
+	}
+	_, err = credentials.GetToken(ProviderGitea, "https://attacker.example.com", "enterprise")
+	if err == nil {
+		t.Fatal("Expected error for credentials of other instance")
+	}
+	_, err = credentials.GetToken(ProviderGitlab, "", "acme")
+	if err == nil {
+		t.Fatal("Expected error for credentials of other provider")
+	}
+	_, err = credentials.GetToken(ProviderGithub, "", "gitlab")
+	if err == nil {
+		t.Fatal("Expected error for credentials of other provider")
+	}
+}
+
+func TestReadCredentialsFile_RequiresType(t *testing.T) {
+	credentialsFile := filepath.Join(t.TempDir(), "credentials.yaml")
+	err := os.WriteFile(credentialsFile, []byte("acme:\n  token: acme-token\n  baseUrl: https://gitlab.example.com\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ReadCredentialsFile(credentialsFile, secrets.NewRedactor())
+	if err == nil {
+		t.Fatal("Expected error for credentials without type")
+	}
+}
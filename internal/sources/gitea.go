@@ -0,0 +1,70 @@
+package sources
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/krystofrezac/lifebuoy/internal/tarball"
+)
+
+type giteaProvider struct {
+	baseUrl string
+	token   TokenSource
+	limits  tarball.Limits
+}
+
+func (g giteaProvider) GetSha(ctx context.Context, owner string, repository string, revision *string) (string, error) {
+	query := url.Values{"limit": {"1"}, "stat": {"false"}, "verification": {"false"}, "files": {"false"}}
+	if revision != nil {
+		query.Set("sha", *revision)
+	}
+
+	headers, err := g.getHeaders(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	res, err := get(ctx, g.getRepositoryUrl(owner, repository)+"/commits?"+query.Encode(), headers)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	var commits []struct {
+		Sha string `json:"sha"`
+	}
+	err = json.NewDecoder(res.Body).Decode(&commits)
+	if err != nil {
+		return "", err
+	}
+	if len(commits) == 0 {
+		return "", fmt.Errorf("Repository `%s/%s` has no commits", owner, repository)
+	}
+	return commits[0].Sha, nil
+}
+
+func (g giteaProvider) DownloadRepository(ctx context.Context, owner string, repository string, revision string, destinationDir string) error {
+	headers, err := g.getHeaders(ctx)
+	if err != nil {
+		return err
+	}
+
+	archiveUrl := g.getRepositoryUrl(owner, repository) + "/archive/" + url.PathEscape(revision) + ".tar.gz"
+	return download(ctx, archiveUrl, headers, destinationDir, g.limits)
+}
+
+// Gitea has no rate limit by default
+func (g giteaProvider) GetPollInterval() time.Duration {
+	return 0
+}
+
+func (g giteaProvider) getRepositoryUrl(owner string, repository string) string {
+	return g.baseUrl + "/api/v1/repos/" + url.PathEscape(owner) + "/" + url.PathEscape(repository)
+}
+
+func (g giteaProvider) getHeaders(ctx context.Context) (map[string]string, error) {
+	return getAuthorizationHeaders(ctx, g.token, "Authorization", "token ")
+}
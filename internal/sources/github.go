@@ -0,0 +1,59 @@
+package sources
+
+import (
+	"context"
+	"time"
+
+	"github.com/krystofrezac/lifebuoy/internal/tarball"
+)
+
+const defaultGithubBaseUrl = "https://api.github.com"
+
+type githubProvider struct {
+	// Github Enterprise has the API under `/api/v3`
+	baseUrl string
+	token   TokenSource
+	limits  tarball.Limits
+	client  *githubClient
+}
+
+func (g githubProvider) GetSha(ctx context.Context, owner string, repository string, revision *string) (string, error) {
+	url := g.baseUrl + "/repos/" + owner + "/" + repository + "/commits/"
+	if revision != nil {
+		url += *revision
+	} else {
+		url += "HEAD"
+	}
+
+	headers, err := g.getHeaders(ctx)
+	if err != nil {
+		return "", err
+	}
+	headers["Accept"] = "application/vnd.github.sha"
+	sha, err := g.client.getCached(ctx, url, headers)
+	return string(sha), err
+}
+
+func (g githubProvider) DownloadRepository(ctx context.Context, owner string, repository string, revision string, destinationDir string) error {
+	headers, err := g.getHeaders(ctx)
+	if err != nil {
+		return err
+	}
+
+	url := g.baseUrl + "/repos/" + owner + "/" + repository + "/tarball/" + revision
+	res, err := g.client.get(ctx, url, headers)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	return tarball.Extract(destinationDir, res.Body, g.limits)
+}
+
+func (g githubProvider) GetPollInterval() time.Duration {
+	return g.client.getPollInterval()
+}
+
+func (g githubProvider) getHeaders(ctx context.Context) (map[string]string, error) {
+	return getAuthorizationHeaders(ctx, g.token, "Authorization", "Bearer ")
+}
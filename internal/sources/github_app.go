@@ -0,0 +1,208 @@
+package sources
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/krystofrezac/lifebuoy/internal/secrets"
+)
+
+// Installation tokens are valid for an hour, they are replaced a bit sooner so requests in progress don't fail
+const githubAppTokenRefreshMargin = 5 * time.Minute
+
+// Github allows at most 10 minutes
+const githubAppJwtLifetime = 9 * time.Minute
+
+// Installation tokens of a Github App. Safe for concurrent use
+type GithubAppTokenSource struct {
+	logger *slog.Logger
+	// Created tokens are registered, so they don't appear in logs
+	redactor *secrets.Redactor
+	// Requests authenticated by the app itself, they have their own rate limit
+	client         *githubClient
+	baseUrl        string
+	appId          string
+	installationId string
+	privateKey     *rsa.PrivateKey
+	mutex          sync.Mutex
+	token          secrets.Value
+	expiresAt      time.Time
+	// Closed when the refresh in progress finishes. nil = no refresh is in progress
+	refreshDone chan struct{}
+}
+
+// baseUrl empty = the public instance. privateKey is the PEM file downloaded from the settings of the app
+func NewGithubAppTokenSource(
+	logger *slog.Logger,
+	redactor *secrets.Redactor,
+	baseUrl string,
+	appId string,
+	installationId string,
+	privateKey []byte,
+) (*GithubAppTokenSource, error) {
+	key, err := parseRsaPrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if baseUrl == "" {
+		baseUrl = defaultGithubBaseUrl
+	}
+	baseUrl = strings.TrimSuffix(baseUrl, "/")
+
+	return &GithubAppTokenSource{
+		logger:         logger,
+		redactor:       redactor,
+		client:         getGithubClient(logger, baseUrl, "app-"+appId),
+		baseUrl:        baseUrl,
+		appId:          appId,
+		installationId: installationId,
+		privateKey:     key,
+	}, nil
+}
+
+// Returns the current installation token, a new one is created when it's about to expire.
+// Only one caller creates it, the others get the current token while it's still valid, or wait
+func (g *GithubAppTokenSource) Token(ctx context.Context) (secrets.Value, error) {
+	for {
+		g.mutex.Lock()
+		token := g.token
+		expiresAt := g.expiresAt
+		if time.Until(expiresAt) > githubAppTokenRefreshMargin {
+			g.mutex.Unlock()
+			return token, nil
+		}
+
+		refreshDone := g.refreshDone
+		if refreshDone == nil {
+			refreshDone = make(chan struct{})
+			g.refreshDone = refreshDone
+			g.mutex.Unlock()
+			return g.refresh(ctx, refreshDone)
+		}
+		g.mutex.Unlock()
+
+		if time.Now().Before(expiresAt) {
+			return token, nil
+		}
+		select {
+		case <-refreshDone:
+		case <-ctx.Done():
+			return secrets.Value{}, ctx.Err()
+		}
+	}
+}
+
+// When the new token can't be created, the current one is returned while it's still valid
+func (g *GithubAppTokenSource) refresh(ctx context.Context, refreshDone chan struct{}) (secrets.Value, error) {
+	token, expiresAt, err := g.createInstallationToken(ctx)
+
+	g.mutex.Lock()
+	if err == nil {
+		g.token = token
+		g.expiresAt = expiresAt
+	} else if time.Now().Before(g.expiresAt) {
+		g.logger.Warn("Failed to refresh installation token of Github App, using the current one", "appId", g.appId, "expiresAt", g.expiresAt, "err", err)
+		token = g.token
+		err = nil
+	}
+	g.refreshDone = nil
+	g.mutex.Unlock()
+	close(refreshDone)
+
+	return token, err
+}
+
+// Installation tokens are replaced, but they share the rate limit of the installation
+func (g *GithubAppTokenSource) Id() string {
+	return "app-" + g.appId + "-installation-" + g.installationId
+}
+
+func (g *GithubAppTokenSource) createInstallationToken(ctx context.Context) (secrets.Value, time.Time, error) {
+	jwt, err := g.createJwt(time.Now())
+	if err != nil {
+		return secrets.Value{}, time.Time{}, err
+	}
+
+	url := g.baseUrl + "/app/installations/" + g.installationId + "/access_tokens"
+	headers := map[string]string{"Authorization": "Bearer " + jwt, "Accept": "application/vnd.github+json"}
+	res, err := g.client.send(ctx, http.MethodPost, url, headers, http.StatusCreated)
+	if err != nil {
+		return secrets.Value{}, time.Time{}, fmt.Errorf("Failed to create installation token of Github App `%s`: %w", g.appId, err)
+	}
+	defer res.Body.Close()
+
+	var body struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	err = json.NewDecoder(res.Body).Decode(&body)
+	if err != nil {
+		return secrets.Value{}, time.Time{}, err
+	}
+
+	token := secrets.NewValue(body.Token)
+	g.redactor.Register(token)
+	return token, body.ExpiresAt, nil
+}
+
+// JWT signed with RS256 that authenticates the app itself
+func (g *GithubAppTokenSource) createJwt(now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(map[string]any{
+		// Backdated against clock drift
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(githubAppJwtLifetime).Unix(),
+		"iss": g.appId,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	hash := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, g.privateKey, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", err
+	}
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// Github generates keys in PKCS #1, PKCS #8 is accepted too
+func parseRsaPrivateKey(encoded []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(encoded)
+	if block == nil {
+		return nil, fmt.Errorf("Private key of Github App isn't in PEM format")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse private key of Github App: %w", err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("Private key of Github App has to be RSA")
+	}
+	return rsaKey, nil
+}
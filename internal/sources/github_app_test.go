@@ -0,0 +1,105 @@
+package sources
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/krystofrezac/lifebuoy/internal/secrets"
+)
+
+func TestGithubAppTokenSource(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encodedKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	requests := 0
+	failing := false
+	expiresAt := time.Now().Add(time.Hour)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if failing {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.Method != http.MethodPost || r.URL.Path != "/app/installations/42/access_tokens" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		jwt, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		parts := strings.Split(jwt, ".")
+		if len(parts) != 3 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+		hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, hash[:], signature) != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"token": "installation-token", "expires_at": "` + expiresAt.Format(time.RFC3339) + `"}`))
+	}))
+	defer server.Close()
+
+	redactor := secrets.NewRedactor()
+	tokenSource, err := NewGithubAppTokenSource(testLogger, redactor, server.URL, "1", "42", encodedKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for range 2 {
+		token, err := tokenSource.Token(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token.Reveal() != "installation-token" {
+			t.Fatalf("Expected installation token, got '%s'", token.Reveal())
+		}
+	}
+	if requests != 1 {
+		t.Fatalf("Expected token to be reused, got %d requests", requests)
+	}
+	if redacted := redactor.Redact("token=installation-token"); strings.Contains(redacted, "installation-token") {
+		t.Fatalf("Expected installation token to be registered in the redactor, got '%s'", redacted)
+	}
+
+	// About to expire
+	tokenSource.expiresAt = time.Now().Add(time.Minute)
+	_, err = tokenSource.Token(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requests != 2 {
+		t.Fatalf("Expected token to be refreshed, got %d requests", requests)
+	}
+
+	// Still valid token is used when the refresh fails
+	failing = true
+	tokenSource.expiresAt = time.Now().Add(time.Minute)
+	token, err := tokenSource.Token(context.Background())
+	if err != nil || token.Reveal() != "installation-token" {
+		t.Fatalf("Expected current token, got '%s' and %v", token.Reveal(), err)
+	}
+
+	tokenSource.expiresAt = time.Now().Add(-time.Minute)
+	_, err = tokenSource.Token(context.Background())
+	if err == nil {
+		t.Fatal("Expected error when the current token expired")
+	}
+}
@@ -0,0 +1,299 @@
+package sources
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Requests that failed with a network error or 5xx are retried with exponential backoff
+const githubMaxAttempts = 4
+
+var githubRetryDelay = time.Second
+
+// Least recently used responses are evicted above this, so revisions that are no longer used don't pile up
+const githubMaxCachedResponses = 1000
+
+// Warning is logged when fewer requests than this share of the limit remain
+const githubLowRateLimitRatio = 0.1
+
+// Served at `/debug/vars` of the HTTP API. Not published in expvar, so the global handler isn't needed
+var githubMetrics = new(expvar.Map).Init()
+
+// JSON object with the metrics
+func GithubMetrics() string {
+	return githubMetrics.String()
+}
+
+// Rate limits by client
+var githubRateLimitMetrics = new(expvar.Map).Init()
+
+func init() {
+	githubMetrics.Set("rateLimits", githubRateLimitMetrics)
+}
+
+// Shared by all Github providers with the same base URL and token, as Github limits requests per token. Safe for concurrent use
+type githubClient struct {
+	logger *slog.Logger
+	// Base URL and id of the token
+	key   string
+	mutex sync.Mutex
+	// Responses of conditional requests by URL. Requests answered with 304 don't count against the rate limit
+	cache map[string]githubCachedResponse
+	// Incremented on every use of the cache, orders the cached responses by their last use
+	cacheUses uint64
+	// Requests aren't sent until then. Zero = not limited
+	blockedUntil time.Time
+	// -1 = unknown
+	rateLimitRemaining int
+	rateLimitLimit     int
+	rateLimitReset     time.Time
+}
+
+type githubCachedResponse struct {
+	etag string
+	body []byte
+	// Value of `cacheUses` when the response was last used
+	usedAt uint64
+}
+
+var githubClientsMutex sync.Mutex
+var githubClients = map[string]*githubClient{}
+
+// The client logs with the logger of the first provider that uses it
+func getGithubClient(logger *slog.Logger, baseUrl string, tokenId string) *githubClient {
+	githubClientsMutex.Lock()
+	defer githubClientsMutex.Unlock()
+
+	key := baseUrl + " " + tokenId
+	client, ok := githubClients[key]
+	if !ok {
+		client = &githubClient{logger: logger, key: key, cache: map[string]githubCachedResponse{}, rateLimitRemaining: -1}
+		githubClients[key] = client
+	}
+	return client
+}
+
+// Returns body of the response. When the resource hasn't changed, the cached body is returned
+func (g *githubClient) getCached(ctx context.Context, url string, headers map[string]string) ([]byte, error) {
+	g.mutex.Lock()
+	cached, isCached := g.cache[url]
+	if isCached {
+		g.cacheUses++
+		cached.usedAt = g.cacheUses
+		g.cache[url] = cached
+	}
+	g.mutex.Unlock()
+
+	if isCached {
+		headers["If-None-Match"] = cached.etag
+	}
+
+	res, err := g.get(ctx, url, headers)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotModified {
+		if !isCached {
+			return nil, fmt.Errorf("Request `GET %s` returned 304 without a cached response", url)
+		}
+		githubMetrics.Add("notModified", 1)
+		return cached.body, nil
+	}
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if etag := res.Header.Get("ETag"); etag != "" {
+		g.mutex.Lock()
+		g.cacheUses++
+		g.cache[url] = githubCachedResponse{etag: etag, body: body, usedAt: g.cacheUses}
+		g.evictCachedResponses()
+		g.mutex.Unlock()
+	}
+	return body, nil
+}
+
+// Must be called with the mutex locked
+func (g *githubClient) evictCachedResponses() {
+	for len(g.cache) > githubMaxCachedResponses {
+		oldestUrl := ""
+		var oldestUsedAt uint64
+		for url, cached := range g.cache {
+			if oldestUrl == "" || cached.usedAt < oldestUsedAt {
+				oldestUrl = url
+				oldestUsedAt = cached.usedAt
+			}
+		}
+		delete(g.cache, oldestUrl)
+	}
+}
+
+// Returns the response only when it has status 200 or 304
+func (g *githubClient) get(ctx context.Context, url string, headers map[string]string) (*http.Response, error) {
+	return g.send(ctx, http.MethodGet, url, headers, http.StatusOK, http.StatusNotModified)
+}
+
+// Request without body. Returns the response only when it has one of the expected statuses
+func (g *githubClient) send(ctx context.Context, method string, url string, headers map[string]string, expectedStatuses ...int) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		res, err := g.sendOnce(ctx, method, url, headers, expectedStatuses)
+		var retryable *githubRetryableError
+		if !errors.As(err, &retryable) || attempt == githubMaxAttempts {
+			return res, err
+		}
+
+		delay := githubRetryDelay << (attempt - 1)
+		githubMetrics.Add("retries", 1)
+		g.logger.Warn("Github request failed, retrying", "client", g.key, "url", url, "attempt", attempt, "delay", delay, "err", retryable.err)
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+type githubRetryableError struct {
+	err error
+}
+
+func (g *githubRetryableError) Error() string {
+	return g.err.Error()
+}
+
+func (g *githubRetryableError) Unwrap() error {
+	return g.err
+}
+
+func (g *githubClient) sendOnce(ctx context.Context, method string, url string, headers map[string]string, expectedStatuses []int) (*http.Response, error) {
+	g.mutex.Lock()
+	blockedUntil := g.blockedUntil
+	g.mutex.Unlock()
+	if time.Now().Before(blockedUntil) {
+		return nil, fmt.Errorf("Github rate limit exceeded, requests are paused until %s", blockedUntil.Format(time.RFC3339))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range headers {
+		req.Header.Add(name, value)
+	}
+
+	githubMetrics.Add("requests", 1)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		return nil, &githubRetryableError{err: err}
+	}
+	g.updateRateLimit(res)
+
+	switch {
+	case slices.Contains(expectedStatuses, res.StatusCode):
+		return res, nil
+	case res.StatusCode >= 500:
+		res.Body.Close()
+		return nil, &githubRetryableError{err: fmt.Errorf("Request `%s %s` failed with status %d", method, url, res.StatusCode)}
+	}
+
+	res.Body.Close()
+	if blockedUntil, ok := g.blockIfRateLimited(res); ok {
+		return nil, fmt.Errorf("Github rate limit exceeded, requests are paused until %s", blockedUntil.Format(time.RFC3339))
+	}
+	return nil, fmt.Errorf("Request `%s %s` failed with status %d", method, url, res.StatusCode)
+}
+
+func (g *githubClient) updateRateLimit(res *http.Response) {
+	remaining, err := strconv.Atoi(res.Header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	limit, _ := strconv.Atoi(res.Header.Get("X-RateLimit-Limit"))
+	reset, _ := strconv.ParseInt(res.Header.Get("X-RateLimit-Reset"), 10, 64)
+
+	metrics := new(expvar.Map).Init()
+	metrics.Set("remaining", intVar(int64(remaining)))
+	metrics.Set("limit", intVar(int64(limit)))
+	metrics.Set("reset", intVar(reset))
+	githubRateLimitMetrics.Set(g.key, metrics)
+
+	g.mutex.Lock()
+	previous := g.rateLimitRemaining
+	g.rateLimitRemaining = remaining
+	g.rateLimitLimit = limit
+	g.rateLimitReset = time.Unix(reset, 0)
+	g.mutex.Unlock()
+
+	threshold := int(float64(limit) * githubLowRateLimitRatio)
+	if remaining < threshold && (previous == -1 || previous >= threshold) {
+		g.logger.Warn("Github rate limit is almost exhausted", "client", g.key, "remaining", remaining, "limit", limit, "reset", time.Unix(reset, 0))
+	}
+}
+
+// Primary limit has no remaining requests, secondary limits respond with `Retry-After`
+func (g *githubClient) blockIfRateLimited(res *http.Response) (time.Time, bool) {
+	if res.StatusCode != http.StatusForbidden && res.StatusCode != http.StatusTooManyRequests {
+		return time.Time{}, false
+	}
+
+	var blockedUntil time.Time
+	if retryAfter, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil {
+		blockedUntil = time.Now().Add(time.Duration(retryAfter) * time.Second)
+	} else if res.Header.Get("X-RateLimit-Remaining") == "0" {
+		reset, err := strconv.ParseInt(res.Header.Get("X-RateLimit-Reset"), 10, 64)
+		if err != nil {
+			return time.Time{}, false
+		}
+		blockedUntil = time.Unix(reset, 0)
+	} else {
+		return time.Time{}, false
+	}
+
+	g.mutex.Lock()
+	g.blockedUntil = blockedUntil
+	g.mutex.Unlock()
+
+	githubMetrics.Add("rateLimited", 1)
+	g.logger.Warn("Github rate limit exceeded, pausing requests", "client", g.key, "until", blockedUntil)
+	return blockedUntil, true
+}
+
+// Minimum time between polls. Remaining requests are spread until the reset once the rate limit is almost exhausted. 0 = not limited
+func (g *githubClient) getPollInterval() time.Duration {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if untilUnblocked := time.Until(g.blockedUntil); untilUnblocked > 0 {
+		return untilUnblocked
+	}
+
+	threshold := int(float64(g.rateLimitLimit) * githubLowRateLimitRatio)
+	untilReset := time.Until(g.rateLimitReset)
+	if g.rateLimitRemaining == -1 || g.rateLimitRemaining >= threshold || untilReset <= 0 {
+		return 0
+	}
+	return untilReset / time.Duration(g.rateLimitRemaining+1)
+}
+
+func intVar(value int64) *expvar.Int {
+	res := new(expvar.Int)
+	res.Set(value)
+	return res
+}
@@ -0,0 +1,174 @@
+package sources
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/krystofrezac/lifebuoy/internal/secrets"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func TestGithubClient_UsesETag(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"etag"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"etag"`)
+		w.Write([]byte("sha"))
+	}))
+	defer server.Close()
+
+	client := getGithubClient(testLogger, server.URL, "anonymous")
+	for range 2 {
+		body, err := client.getCached(context.Background(), server.URL+"/commits/main", map[string]string{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(body) != "sha" {
+			t.Fatalf("Expected 'sha', got '%s'", body)
+		}
+	}
+}
+
+func TestGithubClient_EvictsLeastRecentlyUsedResponses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"etag"`)
+		w.Write([]byte("sha"))
+	}))
+	defer server.Close()
+
+	client := getGithubClient(testLogger, server.URL, "anonymous")
+	for i := range githubMaxCachedResponses + 10 {
+		_, err := client.getCached(context.Background(), server.URL+"/commits/"+strconv.Itoa(i), map[string]string{})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(client.cache) != githubMaxCachedResponses {
+		t.Fatalf("Expected %d cached responses, got %d", githubMaxCachedResponses, len(client.cache))
+	}
+	if _, ok := client.cache[server.URL+"/commits/0"]; ok {
+		t.Fatal("Expected the oldest response to be evicted")
+	}
+	if _, ok := client.cache[server.URL+"/commits/"+strconv.Itoa(githubMaxCachedResponses+9)]; !ok {
+		t.Fatal("Expected the newest response to be cached")
+	}
+}
+
+func TestGithubClient_RetriesServerErrors(t *testing.T) {
+	originalDelay := githubRetryDelay
+	githubRetryDelay = time.Millisecond
+	t.Cleanup(func() { githubRetryDelay = originalDelay })
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte("sha"))
+	}))
+	defer server.Close()
+
+	body, err := getGithubClient(testLogger, server.URL, "anonymous").getCached(context.Background(), server.URL, map[string]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != "sha" || requests != 3 {
+		t.Fatalf("Expected 'sha' after 3 requests, got '%s' after %d", body, requests)
+	}
+}
+
+func TestGithubClient_PausesWhenRateLimited(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	client := getGithubClient(testLogger, server.URL, "anonymous")
+	for range 2 {
+		_, err := client.getCached(context.Background(), server.URL, map[string]string{})
+		if err == nil {
+			t.Fatal("Expected error")
+		}
+	}
+	if requests != 1 {
+		t.Fatalf("Expected requests to be paused, got %d requests", requests)
+	}
+}
+
+func TestGithubClient_RateLimitIsPerToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "Bearer exhausted" {
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte("sha"))
+	}))
+	defer server.Close()
+
+	exhausted, err := NewProvider(testLogger, ProviderOpts{Type: ProviderGithub, BaseUrl: server.URL, Token: NewStaticToken(secrets.NewValue("exhausted"))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewProvider(testLogger, ProviderOpts{Type: ProviderGithub, BaseUrl: server.URL, Token: NewStaticToken(secrets.NewValue("other"))})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = exhausted.GetSha(context.Background(), "owner", "repository", nil)
+	if err == nil {
+		t.Fatal("Expected error")
+	}
+	sha, err := other.GetSha(context.Background(), "owner", "repository", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sha != "sha" {
+		t.Fatalf("Expected 'sha', got '%s'", sha)
+	}
+}
+
+func TestGithubClient_SlowsPollingDownWhenRateLimitIsLow(t *testing.T) {
+	remaining := "4000"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", remaining)
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+		w.Write([]byte("sha"))
+	}))
+	defer server.Close()
+
+	client := getGithubClient(testLogger, server.URL, "anonymous")
+	_, err := client.getCached(context.Background(), server.URL, map[string]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if interval := client.getPollInterval(); interval != 0 {
+		t.Fatalf("Expected polling not to be slowed down, got %s", interval)
+	}
+
+	remaining = "9"
+	_, err = client.getCached(context.Background(), server.URL, map[string]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if interval := client.getPollInterval(); interval < 5*time.Minute || interval > 6*time.Minute {
+		t.Fatalf("Expected remaining requests to be spread until the reset, got %s", interval)
+	}
+}
@@ -0,0 +1,81 @@
+package sources
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"time"
+
+	"github.com/krystofrezac/lifebuoy/internal/tarball"
+)
+
+const defaultGitlabBaseUrl = "https://gitlab.com"
+
+type gitlabProvider struct {
+	baseUrl string
+	token   TokenSource
+	limits  tarball.Limits
+}
+
+func (g gitlabProvider) GetSha(ctx context.Context, owner string, repository string, revision *string) (string, error) {
+	if revision == nil {
+		defaultBranch, err := g.getDefaultBranch(ctx, owner, repository)
+		if err != nil {
+			return "", err
+		}
+		revision = &defaultBranch
+	}
+
+	var commit struct {
+		Id string `json:"id"`
+	}
+	err := g.getJson(ctx, g.getProjectUrl(owner, repository)+"/repository/commits/"+url.PathEscape(*revision), &commit)
+	return commit.Id, err
+}
+
+func (g gitlabProvider) DownloadRepository(ctx context.Context, owner string, repository string, revision string, destinationDir string) error {
+	headers, err := g.getHeaders(ctx)
+	if err != nil {
+		return err
+	}
+
+	archiveUrl := g.getProjectUrl(owner, repository) + "/repository/archive.tar.gz?sha=" + url.QueryEscape(revision)
+	return download(ctx, archiveUrl, headers, destinationDir, g.limits)
+}
+
+func (g gitlabProvider) getDefaultBranch(ctx context.Context, owner string, repository string) (string, error) {
+	var project struct {
+		DefaultBranch string `json:"default_branch"`
+	}
+	err := g.getJson(ctx, g.getProjectUrl(owner, repository), &project)
+	return project.DefaultBranch, err
+}
+
+// owner can be a group with subgroups, e.g. `group/subgroup`
+func (g gitlabProvider) getProjectUrl(owner string, repository string) string {
+	return g.baseUrl + "/api/v4/projects/" + url.PathEscape(owner+"/"+repository)
+}
+
+func (g gitlabProvider) getJson(ctx context.Context, requestUrl string, out any) error {
+	headers, err := g.getHeaders(ctx)
+	if err != nil {
+		return err
+	}
+
+	res, err := get(ctx, requestUrl, headers)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+// Rate limits of GitLab aren't tracked
+func (g gitlabProvider) GetPollInterval() time.Duration {
+	return 0
+}
+
+func (g gitlabProvider) getHeaders(ctx context.Context) (map[string]string, error) {
+	return getAuthorizationHeaders(ctx, g.token, "PRIVATE-TOKEN", "")
+}
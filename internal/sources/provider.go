@@ -0,0 +1,106 @@
+package sources
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/krystofrezac/lifebuoy/internal/tarball"
+)
+
+// Host of git repositories that apps and the configuration are downloaded from
+type Provider interface {
+	// Resolves a branch, tag or commit to the commit sha. revision nil = the default branch
+	GetSha(ctx context.Context, owner string, repository string, revision *string) (string, error)
+	// Downloads content of the repository at the revision into destinationDir
+	DownloadRepository(ctx context.Context, owner string, repository string, revision string, destinationDir string) error
+	// Minimum time between polls of the provider, so its rate limit isn't exhausted. 0 = polling isn't slowed down
+	GetPollInterval() time.Duration
+}
+
+type ProviderType string
+
+const (
+	ProviderGithub ProviderType = "github"
+	ProviderGitlab ProviderType = "gitlab"
+	// Forgejo has the same API
+	ProviderGitea ProviderType = "gitea"
+)
+
+type ProviderOpts struct {
+	Type ProviderType
+	// Empty = the public instance, required for Gitea
+	BaseUrl string
+	// nil = anonymous access
+	Token TokenSource
+	// Of downloaded repositories. Doesn't affect resolving of revisions
+	Limits tarball.Limits
+}
+
+func NewProvider(logger *slog.Logger, opts ProviderOpts) (Provider, error) {
+	baseUrl := strings.TrimSuffix(opts.BaseUrl, "/")
+
+	switch opts.Type {
+	case ProviderGithub:
+		if baseUrl == "" {
+			baseUrl = defaultGithubBaseUrl
+		}
+		return githubProvider{baseUrl: baseUrl, token: opts.Token, limits: opts.Limits, client: getGithubClient(logger, baseUrl, getTokenId(opts.Token))}, nil
+	case ProviderGitlab:
+		if baseUrl == "" {
+			baseUrl = defaultGitlabBaseUrl
+		}
+		return gitlabProvider{baseUrl: baseUrl, token: opts.Token, limits: opts.Limits}, nil
+	case ProviderGitea:
+		if baseUrl == "" {
+			return nil, fmt.Errorf("Provider `%s` requires base URL", opts.Type)
+		}
+		return giteaProvider{baseUrl: baseUrl, token: opts.Token, limits: opts.Limits}, nil
+	}
+
+	return nil, fmt.Errorf("Unknown provider `%s`", opts.Type)
+}
+
+// Identifies the instance of the provider, e.g. for caching
+func (p ProviderOpts) Key() string {
+	return string(p.Type) + " " + strings.TrimSuffix(p.BaseUrl, "/")
+}
+
+// Identifies the token, without revealing it. Responses can differ by token, e.g. private refs
+func (p ProviderOpts) TokenId() string {
+	return getTokenId(p.Token)
+}
+
+// Returns the response only when it has status 200
+func get(ctx context.Context, url string, headers map[string]string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range headers {
+		req.Header.Add(name, value)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, fmt.Errorf("Request `GET %s` failed with status %d", url, res.StatusCode)
+	}
+	return res, nil
+}
+
+func download(ctx context.Context, url string, headers map[string]string, destinationDir string, limits tarball.Limits) error {
+	res, err := get(ctx, url, headers)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	return tarball.Extract(destinationDir, res.Body, limits)
+}
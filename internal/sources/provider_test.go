@@ -0,0 +1,91 @@
+package sources
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/krystofrezac/lifebuoy/internal/secrets"
+)
+
+func TestGetSha(t *testing.T) {
+	sha := "0123456789abcdef0123456789abcdef01234567"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.RequestURI() {
+		case "/repos/owner/repository/commits/main":
+			if r.Header.Get("Authorization") != "Bearer token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(sha))
+		case "/api/v4/projects/group%2Fsubgroup%2Frepository":
+			w.Write([]byte(`{"default_branch": "main"}`))
+		case "/api/v4/projects/group%2Fsubgroup%2Frepository/repository/commits/main":
+			w.Write([]byte(`{"id": "` + sha + `"}`))
+		case "/api/v1/repos/owner/repository/commits?files=false&limit=1&sha=main&stat=false&verification=false":
+			w.Write([]byte(`[{"sha": "` + sha + `"}]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	token := NewStaticToken(secrets.NewValue("token"))
+	main := "main"
+	tests := []struct {
+		opts     ProviderOpts
+		owner    string
+		revision *string
+	}{
+		{ProviderOpts{Type: ProviderGithub, BaseUrl: server.URL, Token: token}, "owner", &main},
+		{ProviderOpts{Type: ProviderGitlab, BaseUrl: server.URL}, "group/subgroup", nil},
+		{ProviderOpts{Type: ProviderGitea, BaseUrl: server.URL + "/"}, "owner", &main},
+	}
+
+	for _, test := range tests {
+		provider, err := NewProvider(testLogger, test.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := provider.GetSha(context.Background(), test.owner, "repository", test.revision)
+		if err != nil {
+			t.Fatalf("Provider `%s` failed: %s", test.opts.Type, err)
+		}
+		if res != sha {
+			t.Fatalf("Expected '%s' from provider `%s`, got '%s'", sha, test.opts.Type, res)
+		}
+	}
+}
+
+func TestGetSha_ErrorDoesNotContainToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	token := secrets.NewValue("very-secret-token")
+	for _, providerType := range []ProviderType{ProviderGithub, ProviderGitlab, ProviderGitea} {
+		provider, err := NewProvider(testLogger, ProviderOpts{Type: providerType, BaseUrl: server.URL, Token: NewStaticToken(token)})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = provider.GetSha(context.Background(), "owner", "repository", nil)
+		if err == nil {
+			t.Fatalf("Expected error from provider `%s`", providerType)
+		}
+		if strings.Contains(err.Error(), token.Reveal()) {
+			t.Fatalf("Error of provider `%s` contains the token: %s", providerType, err)
+		}
+	}
+}
+
+func TestNewProvider_GiteaRequiresBaseUrl(t *testing.T) {
+	_, err := NewProvider(testLogger, ProviderOpts{Type: ProviderGitea})
+	if err == nil {
+		t.Fatal("Expected error")
+	}
+}
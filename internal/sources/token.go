@@ -0,0 +1,59 @@
+package sources
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/krystofrezac/lifebuoy/internal/secrets"
+)
+
+// Provides tokens for requests to the provider. Safe for concurrent use
+type TokenSource interface {
+	Token(ctx context.Context) (secrets.Value, error)
+	// Identifies the token without revealing it, e.g. for rate limits that are per token
+	Id() string
+}
+
+type staticToken struct {
+	value secrets.Value
+}
+
+// Token that never changes, e.g. a personal access token
+func NewStaticToken(value secrets.Value) TokenSource {
+	return staticToken{value: value}
+}
+
+func (s staticToken) Token(ctx context.Context) (secrets.Value, error) {
+	return s.value, nil
+}
+
+// Prefix of the hash of the token
+func (s staticToken) Id() string {
+	hash := sha256.Sum256([]byte(s.value.Reveal()))
+	return "token-" + hex.EncodeToString(hash[:6])
+}
+
+// tokenSource nil = anonymous requests
+func getTokenId(tokenSource TokenSource) string {
+	if tokenSource == nil {
+		return "anonymous"
+	}
+	return tokenSource.Id()
+}
+
+// tokenSource nil = no headers. Value of the header is the token with the prefix
+func getAuthorizationHeaders(ctx context.Context, tokenSource TokenSource, header string, prefix string) (map[string]string, error) {
+	headers := map[string]string{}
+	if tokenSource == nil {
+		return headers, nil
+	}
+
+	token, err := tokenSource.Token(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get token: %w", err)
+	}
+	headers[header] = prefix + token.Reveal()
+	return headers, nil
+}
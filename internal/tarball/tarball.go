@@ -0,0 +1,250 @@
+package tarball
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Limits of a single archive, so a huge repository can't fill the disk
+type Limits struct {
+	// Bytes of all files after extraction
+	MaxTotalSize int64
+	MaxFileCount int
+}
+
+const DefaultMaxTotalSize int64 = 1 << 30
+const DefaultMaxFileCount = 100_000
+
+var errTooLarge = errors.New("Archive is too large")
+
+var firstDirNameRegex = regexp.MustCompile("^[^/]*/")
+
+// Extracts gzipped tarball of a repository. The top level directory of the archive is stripped, as providers name it after the repository and revision.
+// Entries and links pointing outside of destinationDir are rejected, as are archives over the limits
+func Extract(destinationDir string, tarSource io.Reader, limits Limits) error {
+	gzr, err := gzip.NewReader(tarSource)
+	if err != nil {
+		return err
+	}
+	defer gzr.Close()
+
+	err = os.MkdirAll(destinationDir, 0755)
+	if err != nil {
+		return err
+	}
+
+	tr := tar.NewReader(gzr)
+	var totalSize int64
+	fileCount := 0
+	// Created after everything else, when targets of all symlinks are known
+	var symlinks []*tar.Header
+	symlinkNames := map[string]bool{}
+
+	for {
+		header, err := tr.Next()
+
+		switch {
+		case err == io.EOF:
+			return createSymlinks(destinationDir, symlinks, symlinkNames)
+
+		case err != nil:
+			return err
+
+		case header == nil:
+			continue
+		}
+
+		name, err := getEntryName(header.Name)
+		if err != nil {
+			return err
+		}
+		// The stripped top level directory
+		if name == "." {
+			continue
+		}
+
+		fileCount++
+		if fileCount > limits.MaxFileCount {
+			return fmt.Errorf("Archive has more than %d files", limits.MaxFileCount)
+		}
+
+		target := filepath.Join(destinationDir, name)
+		err = checkParents(destinationDir, name)
+		if err != nil {
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+
+		case tar.TypeReg:
+			written, err := writeFile(target, tr, os.FileMode(header.Mode).Perm(), limits.MaxTotalSize-totalSize)
+			if errors.Is(err, errTooLarge) {
+				return fmt.Errorf("Archive is larger than %d bytes", limits.MaxTotalSize)
+			}
+			if err != nil {
+				return err
+			}
+			totalSize += written
+
+		case tar.TypeSymlink:
+			header.Name = name
+			symlinks = append(symlinks, header)
+			symlinkNames[name] = true
+
+		case tar.TypeLink:
+			// Relative to the root of the archive
+			linkName, err := getEntryName(header.Linkname)
+			if err != nil {
+				return err
+			}
+			if err := checkParents(destinationDir, linkName); err != nil {
+				return err
+			}
+			source := filepath.Join(destinationDir, linkName)
+			if err := replace(target, func() error { return os.Link(source, target) }); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func createSymlinks(destinationDir string, symlinks []*tar.Header, symlinkNames map[string]bool) error {
+	for _, header := range symlinks {
+		err := checkSymlink(header.Name, header.Linkname, symlinkNames)
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(destinationDir, header.Name)
+		err = replace(target, func() error { return os.Symlink(header.Linkname, target) })
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Target is resolved component by component, relative to the directory of the link. It can't get above the root, nor pass through
+// another symlink, as `..` after a symlink leads elsewhere than the path suggests. Only the last component can be a symlink, it's checked on its own
+func checkSymlink(name string, linkname string, symlinkNames map[string]bool) error {
+	if filepath.IsAbs(linkname) {
+		return fmt.Errorf("Symlink `%s` points outside of the repository", name)
+	}
+
+	parts := append(strings.Split(filepath.Dir(name), "/"), strings.Split(linkname, "/")...)
+	var resolved []string
+	for i, part := range parts {
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			if len(resolved) == 0 {
+				return fmt.Errorf("Symlink `%s` points outside of the repository", name)
+			}
+			resolved = resolved[:len(resolved)-1]
+			continue
+		}
+
+		resolved = append(resolved, part)
+		if i < len(parts)-1 && symlinkNames[strings.Join(resolved, "/")] {
+			return fmt.Errorf("Symlink `%s` points through symlink `%s`", name, strings.Join(resolved, "/"))
+		}
+	}
+	return nil
+}
+
+// Name of the entry relative to the destination, without the top level directory
+func getEntryName(headerName string) (string, error) {
+	if filepath.IsAbs(headerName) {
+		return "", fmt.Errorf("Archive entry `%s` has absolute path", headerName)
+	}
+
+	name := firstDirNameRegex.ReplaceAllString(headerName, "")
+	if name == "" {
+		return ".", nil
+	}
+	if !filepath.IsLocal(name) {
+		return "", fmt.Errorf("Archive entry `%s` points outside of the repository", headerName)
+	}
+	return filepath.Clean(name), nil
+}
+
+// Entries are never written through a symlink extracted earlier, otherwise a symlink that is confined to the root could be used to escape it
+func checkParents(destinationDir string, name string) error {
+	parent := destinationDir
+	for _, part := range strings.Split(filepath.Dir(name), string(filepath.Separator)) {
+		if part == "." {
+			continue
+		}
+		parent = filepath.Join(parent, part)
+
+		info, err := os.Lstat(parent)
+		if errors.Is(err, fs.ErrNotExist) {
+			return os.MkdirAll(filepath.Join(destinationDir, filepath.Dir(name)), 0755)
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			return fmt.Errorf("Archive entry `%s` is inside of a symlink", name)
+		}
+	}
+	return nil
+}
+
+// Returns number of written bytes. Fails when the content is longer than maxSize
+func writeFile(target string, content io.Reader, mode fs.FileMode, maxSize int64) (int64, error) {
+	// The target would be followed otherwise
+	err := removeSymlink(target)
+	if err != nil {
+		return 0, err
+	}
+
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	written, err := io.CopyN(f, content, maxSize+1)
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+	if written > maxSize {
+		return 0, errTooLarge
+	}
+
+	return written, f.Close()
+}
+
+// Links can't be created over existing files
+func replace(target string, create func() error) error {
+	info, err := os.Lstat(target)
+	if err == nil && !info.IsDir() {
+		err = os.Remove(target)
+		if err != nil {
+			return err
+		}
+	}
+	return create()
+}
+
+func removeSymlink(target string) error {
+	info, err := os.Lstat(target)
+	if err != nil || info.Mode()&fs.ModeSymlink == 0 {
+		return nil
+	}
+	return os.Remove(target)
+}
@@ -0,0 +1,142 @@
+package tarball
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type entry struct {
+	name     string
+	typeflag byte
+	content  string
+	linkname string
+}
+
+func createArchive(t *testing.T, entries []entry) *bytes.Buffer {
+	buffer := &bytes.Buffer{}
+	gzw := gzip.NewWriter(buffer)
+	tw := tar.NewWriter(gzw)
+	for _, e := range entries {
+		err := tw.WriteHeader(&tar.Header{
+			Name:     e.name,
+			Typeflag: e.typeflag,
+			Linkname: e.linkname,
+			Mode:     0644,
+			Size:     int64(len(e.content)),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = tw.Write([]byte(e.content))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gzw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buffer
+}
+
+func TestExtract_Rejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []entry
+	}{
+		{"parent directory", []entry{{name: "repo/../../evil", typeflag: tar.TypeReg}}},
+		{"parent directory after stripping", []entry{{name: "repo/a/../../evil", typeflag: tar.TypeReg}}},
+		{"absolute path", []entry{{name: "/etc/evil", typeflag: tar.TypeReg}}},
+		{"absolute symlink", []entry{{name: "repo/link", typeflag: tar.TypeSymlink, linkname: "/etc/passwd"}}},
+		{"escaping symlink", []entry{{name: "repo/a/link", typeflag: tar.TypeSymlink, linkname: "../../evil"}}},
+		{"file inside of symlink", []entry{
+			{name: "repo/link", typeflag: tar.TypeSymlink, linkname: "."},
+			{name: "repo/link/evil", typeflag: tar.TypeReg},
+		}},
+		{"chained symlinks", []entry{
+			{name: "repo/a", typeflag: tar.TypeSymlink, linkname: "."},
+			{name: "repo/a/b", typeflag: tar.TypeSymlink, linkname: "../evil"},
+		}},
+		{"symlink through symlink", []entry{
+			{name: "repo/b", typeflag: tar.TypeSymlink, linkname: "."},
+			{name: "repo/a", typeflag: tar.TypeSymlink, linkname: "b/.."},
+		}},
+		{"symlink through later symlink", []entry{
+			{name: "repo/a", typeflag: tar.TypeSymlink, linkname: "b/.."},
+			{name: "repo/b", typeflag: tar.TypeSymlink, linkname: "."},
+		}},
+		{"escaping hardlink", []entry{{name: "repo/link", typeflag: tar.TypeLink, linkname: "repo/../../evil"}}},
+		{"too large", []entry{{name: "repo/a", typeflag: tar.TypeReg, content: "0123456789a"}}},
+		{"too large in total", []entry{
+			{name: "repo/a", typeflag: tar.TypeReg, content: "01234"},
+			{name: "repo/b", typeflag: tar.TypeReg, content: "567890"},
+		}},
+		{"too many files", []entry{
+			{name: "repo/a", typeflag: tar.TypeReg},
+			{name: "repo/b", typeflag: tar.TypeReg},
+			{name: "repo/c", typeflag: tar.TypeReg},
+			{name: "repo/d", typeflag: tar.TypeReg},
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := t.TempDir()
+			destinationDir := filepath.Join(root, "a", "b")
+
+			err := Extract(destinationDir, createArchive(t, test.entries), Limits{MaxTotalSize: 10, MaxFileCount: 3})
+			if err == nil {
+				t.Fatal("Expected error")
+			}
+			if _, err := os.Lstat(filepath.Join(root, "evil")); err == nil {
+				t.Fatal("File outside of the destination was created")
+			}
+			if _, err := os.Lstat(filepath.Join(root, "a", "evil")); err == nil {
+				t.Fatal("File outside of the destination was created")
+			}
+		})
+	}
+}
+
+func TestExtract(t *testing.T) {
+	destinationDir := t.TempDir()
+	err := os.WriteFile(filepath.Join(destinationDir, "file"), []byte("longer old content"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Extract(destinationDir, createArchive(t, []entry{
+		{name: "repo/", typeflag: tar.TypeDir},
+		{name: "repo/file", typeflag: tar.TypeReg, content: "content"},
+		{name: "repo/dir/", typeflag: tar.TypeDir},
+		{name: "repo/dir/symlink", typeflag: tar.TypeSymlink, linkname: "../file"},
+		{name: "repo/hardlink", typeflag: tar.TypeLink, linkname: "repo/file"},
+	}), Limits{MaxTotalSize: DefaultMaxTotalSize, MaxFileCount: DefaultMaxFileCount})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"file", "dir/symlink", "hardlink"} {
+		content, err := os.ReadFile(filepath.Join(destinationDir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != "content" {
+			t.Fatalf("Expected '%s' to contain 'content', got '%s'", name, content)
+		}
+	}
+
+	linkname, err := os.Readlink(filepath.Join(destinationDir, "dir", "symlink"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if linkname != "../file" {
+		t.Fatalf("Expected symlink to '../file', got '%s'", linkname)
+	}
+}